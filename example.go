@@ -3,7 +3,10 @@ package main
 import (
 	"context"
 	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/fsmviz"
+	"github.com/FingerLiu/go-fsm/fsmviz/graphviz"
 	"log"
+	"os"
 )
 
 type OrderType string
@@ -47,6 +50,9 @@ func NewOrder(id int, name string, orderType OrderType) *Order {
 		AddStates(OrderStatusCreated, OrderStatusCancelled,
 			OrderStatusPaid, OrderStatusCheckout,
 			OrderStatusDelivering, OrderStatusDelivered, OrderStatusFinished).
+		// declare where an order starts and ends
+		SetInitial(OrderStatusCreated).
+		AddFinalStates(OrderStatusFinished, OrderStatusCancelled).
 		//add transition from S to E with condition check C
 		AddTransition(OrderStatusCreated, OrderStatusCancelled).
 		AddTransition(OrderStatusCreated, OrderStatusPaid).
@@ -87,8 +93,14 @@ func (o *Order) GetCurrentStatus() string {
 }
 
 // output graphviz visualization
-func (o *Order) VisualizeFsm(filename string) {
-	o.fsm.RenderGraphvizImage(filename)
+func (o *Order) VisualizeFsm(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return graphviz.Render(file, fsmviz.FromFSM(o.fsm), fsmviz.FormatPNG,
+		fsmviz.WithCurrentState(o.GetCurrentStatus()))
 }
 
 func (o *Order) saveStatus(ctx context.Context, status string) {
@@ -131,6 +143,8 @@ func main() {
 	orderVirtual.Transit(OrderStatusCancelled)
 	log.Printf("[order] order status is %s\n", orderVirtual.GetCurrentStatus())
 
-	//order.fsm.RenderGraphvizDot()
-	order.VisualizeFsm("./demo.png")
+	//fsmviz.Render(os.Stdout, fsmviz.FromFSM(order.fsm), fsmviz.FormatSVG)
+	if err := order.VisualizeFsm("./demo.png"); err != nil {
+		log.Println(err)
+	}
 }
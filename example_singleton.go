@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"github.com/FingerLiu/go-fsm/fsmviz"
+	"github.com/FingerLiu/go-fsm/fsmviz/graphviz"
 	fsm "github.com/FingerLiu/go-fsm/singletonfsm"
 	"log"
+	"os"
 )
 
 type OrderType string
@@ -56,6 +59,9 @@ func NewOrderV2Service() *OrderV2Service {
 		AddStates(OrderStatusCreated, OrderStatusCancelled,
 			OrderStatusPaid, OrderStatusCheckout,
 			OrderStatusDelivering, OrderStatusDelivered, OrderStatusFinished).
+		// declare where an order starts and ends
+		SetInitial(OrderStatusCreated).
+		AddFinalStates(OrderStatusFinished, OrderStatusCancelled).
 		//add transition from S to E with condition check C
 		AddTransition(OrderStatusCreated, OrderStatusCancelled).
 		AddTransition(OrderStatusCreated, OrderStatusPaid).
@@ -85,8 +91,13 @@ func (o *OrderV2Service) Transit(ctx context.Context, from, to string) error {
 }
 
 // output graphviz visualization
-func (o *OrderV2Service) VisualizeFsm(filename string) {
-	o.fsm.RenderGraphvizImage(filename)
+func (o *OrderV2Service) VisualizeFsm(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return graphviz.Render(file, fsmviz.FromSingletonFSM(o.fsm), fsmviz.FormatPNG)
 }
 
 func (o *OrderV2Service) saveStatus(ctx context.Context, status string) {
@@ -138,6 +149,8 @@ func main() {
 	orderV2Service.Transit(ctx2, orderVirtual.Status, OrderStatusCancelled)
 	log.Printf("[order] order status is %s\n", orderVirtual.Status)
 
-	//orderV2Service.fsm.RenderGraphvizDot()
-	orderV2Service.VisualizeFsm("./demoV2.png")
+	//fsmviz.Render(os.Stdout, fsmviz.FromSingletonFSM(orderV2Service.fsm), fsmviz.FormatSVG)
+	if err := orderV2Service.VisualizeFsm("./demoV2.png"); err != nil {
+		log.Println(err)
+	}
 }
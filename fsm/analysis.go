@@ -0,0 +1,238 @@
+package fsm
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	case SeverityError:
+		return "error"
+	}
+	return fmt.Sprintf("severity(%d)", int(s))
+}
+
+// finding codes reported by Analyze
+const (
+	FindingNoFinalState        = "no-final-state"
+	FindingUnreachableState    = "unreachable-state"
+	FindingDeadEnd             = "dead-end"
+	FindingCannotFinish        = "cannot-finish"
+	FindingDuplicateTransition = "duplicate-transition"
+	FindingShadowedTransition  = "shadowed-transition"
+	FindingUnknownHookState    = "unknown-hook-state"
+)
+
+// Finding is an issue of the fsm definition found by Analyze.
+type Finding struct {
+	Severity   Severity
+	Code       string
+	State      string
+	Transition string
+	Message    string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("[%s] %s: %s", f.Severity, f.Code, f.Message)
+}
+
+// ValidationError is returned by Validate, it holds findings of SeverityError.
+type ValidationError struct {
+	Findings []Finding
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Findings))
+	for _, f := range e.Findings {
+		messages = append(messages, f.String())
+	}
+	return fmt.Sprintf("[fsm] invalid definition: %s", strings.Join(messages, "; "))
+}
+
+// hookRef is a hook added to a state that is not defined.
+type hookRef struct {
+	state string
+	kind  string
+}
+
+// Validate returns a *ValidationError if Analyze finds any error.
+func (f *FSM) Validate() error {
+	var errs []Finding
+	for _, finding := range f.Analyze() {
+		if finding.Severity == SeverityError {
+			errs = append(errs, finding)
+		}
+	}
+	if len(errs) > 0 {
+		return &ValidationError{Findings: errs}
+	}
+	return nil
+}
+
+// Analyze checks the graph built by AddTransition, it does not evaluate conditions.
+// The initial state is the one declared by SetInitial, otherwise the first added state.
+// Final states are the ones declared by AddFinalStates or tagged TagTerminal.
+func (f *FSM) Analyze() []Finding {
+	var findings []Finding
+	if len(f.states) == 0 {
+		return findings
+	}
+
+	initial := f.states[0]
+	if f.initialState != nil {
+		initial = f.initialState
+	}
+	reachable := f.reachableFrom(initial.Name)
+	for _, s := range f.states {
+		if !reachable[s.Name] {
+			findings = append(findings, Finding{
+				Severity: SeverityWarning,
+				Code:     FindingUnreachableState,
+				State:    s.Name,
+				Message:  fmt.Sprintf("state %s is unreachable from initial state %s", s.Name, initial.Name),
+			})
+		}
+	}
+
+	finals := f.finalStateNames()
+	if len(finals) == 0 {
+		findings = append(findings, Finding{
+			Severity: SeverityInfo,
+			Code:     FindingNoFinalState,
+			Message:  "no final state declared, dead end checks skipped",
+		})
+	} else {
+		canFinish := f.reachingAny(finals)
+		for _, s := range f.states {
+			if finals[s.Name] {
+				continue
+			}
+			if len(f.getAvailableTransitions(s.Name)) == 0 {
+				findings = append(findings, Finding{
+					Severity: SeverityError,
+					Code:     FindingDeadEnd,
+					State:    s.Name,
+					Message:  fmt.Sprintf("non-final state %s has no outgoing transition", s.Name),
+				})
+			} else if !canFinish[s.Name] {
+				findings = append(findings, Finding{
+					Severity: SeverityWarning,
+					Code:     FindingCannotFinish,
+					State:    s.Name,
+					Message:  fmt.Sprintf("state %s can not reach any final state", s.Name),
+				})
+			}
+		}
+	}
+
+	for _, skipped := range f.skippedTransitions {
+		existing := f.getTransition(skipped.From.Name, skipped.To.Name)
+		if sameCondition(existing.Condition, skipped.Condition) {
+			findings = append(findings, Finding{
+				Severity:   SeverityWarning,
+				Code:       FindingDuplicateTransition,
+				Transition: skipped.Key,
+				Message:    fmt.Sprintf("transition %s is added more than once", skipped.Key),
+			})
+		} else {
+			findings = append(findings, Finding{
+				Severity:   SeverityError,
+				Code:       FindingShadowedTransition,
+				Transition: skipped.Key,
+				Message:    fmt.Sprintf("transition %s is added again with another condition, which is ignored", skipped.Key),
+			})
+		}
+	}
+
+	for _, hook := range f.unknownHooks {
+		findings = append(findings, Finding{
+			Severity: SeverityError,
+			Code:     FindingUnknownHookState,
+			State:    hook.state,
+			Message:  fmt.Sprintf("%s hook is added to undefined state %s", hook.kind, hook.state),
+		})
+	}
+	return findings
+}
+
+func (f *FSM) finalStateNames() map[string]bool {
+	finals := make(map[string]bool)
+	for _, s := range f.states {
+		if s.final || s.HasTag(TagTerminal) {
+			finals[s.Name] = true
+		}
+	}
+	return finals
+}
+
+// only check transition link, do not check condition
+func (f *FSM) reachableFrom(state string) map[string]bool {
+	reachable := map[string]bool{state: true}
+	queue := []string{state}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, s := range f.getAvailableStates(current) {
+			if !reachable[s.Name] {
+				reachable[s.Name] = true
+				queue = append(queue, s.Name)
+			}
+		}
+	}
+	return reachable
+}
+
+// reachingAny returns states from which one of targets can be reached, targets included.
+func (f *FSM) reachingAny(targets map[string]bool) map[string]bool {
+	reaching := make(map[string]bool)
+	var queue []string
+	for state := range targets {
+		reaching[state] = true
+		queue = append(queue, state)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, t := range f.transitions {
+			if t.To.Name == current && !reaching[t.From.Name] {
+				reaching[t.From.Name] = true
+				queue = append(queue, t.From.Name)
+			}
+		}
+	}
+	return reaching
+}
+
+// closureName matches function names of closures and method values,
+// their values share code so the code pointer does not identify them.
+var closureName = regexp.MustCompile(`\.func\d+(\.\d+)*$|-fm$`)
+
+// sameCondition reports whether a and b are surely the same function,
+// only top level functions can be told apart, closures and method values count as different.
+func sameCondition(a, b func(ctx context.Context, currentState string) (bool, error)) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	pointer := reflect.ValueOf(a).Pointer()
+	if pointer != reflect.ValueOf(b).Pointer() {
+		return false
+	}
+	return !closureName.MatchString(runtime.FuncForPC(pointer).Name())
+}
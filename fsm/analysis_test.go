@@ -0,0 +1,100 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"testing"
+)
+
+func allow(ctx context.Context, state string) (bool, error) {
+	return true, nil
+}
+
+func role(name string) func(ctx context.Context, state string) (bool, error) {
+	return func(ctx context.Context, state string) (bool, error) {
+		return name == "admin", nil
+	}
+}
+
+func findingCodes(findings []fsm.Finding) map[string]fsm.Severity {
+	codes := make(map[string]fsm.Severity)
+	for _, f := range findings {
+		codes[f.Code] = f.Severity
+	}
+	return codes
+}
+
+func TestAnalyzeDuplicateTransition(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "t").AddStates("a", "b").
+		AddTransitionOn("a", "b", allow).
+		AddTransitionOn("a", "b", allow)
+	codes := findingCodes(f.Analyze())
+	if codes[fsm.FindingDuplicateTransition] != fsm.SeverityWarning {
+		t.Errorf("same function twice is not a duplicate warning: %v", f.Analyze())
+	}
+	if _, ok := codes[fsm.FindingShadowedTransition]; ok {
+		t.Errorf("same function twice is reported as shadowed: %v", f.Analyze())
+	}
+}
+
+func TestAnalyzeShadowedClosure(t *testing.T) {
+	// both closures share code, they must not be taken for the same condition
+	f := fsm.NewFSM(context.Background(), "t").AddStates("a", "b").
+		AddTransitionOn("a", "b", role("admin")).
+		AddTransitionOn("a", "b", role("guest"))
+	if codes := findingCodes(f.Analyze()); codes[fsm.FindingShadowedTransition] != fsm.SeverityError {
+		t.Errorf("second closure is not reported as shadowed: %v", f.Analyze())
+	}
+	var validation *fsm.ValidationError
+	if err := f.Validate(); !errors.As(err, &validation) {
+		t.Errorf("Validate() = %v, want a *ValidationError", err)
+	}
+}
+
+func TestAnalyzeShadowedGuard(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "t").AddStates("a", "b").
+		AddTransitionGuard("a", "b", fsm.HasRole("admin")).
+		AddTransitionGuard("a", "b", fsm.HasRole("guest"))
+	if codes := findingCodes(f.Analyze()); codes[fsm.FindingShadowedTransition] != fsm.SeverityError {
+		t.Errorf("second guard is not reported as shadowed: %v", f.Analyze())
+	}
+
+	f = fsm.NewFSM(context.Background(), "t").AddStates("a", "b").
+		AddTransitionGuard("a", "b", fsm.Condition(allow)).
+		AddTransitionOn("a", "b", allow)
+	if codes := findingCodes(f.Analyze()); codes[fsm.FindingDuplicateTransition] != fsm.SeverityWarning {
+		t.Errorf("same condition as a guard is not a duplicate: %v", f.Analyze())
+	}
+}
+
+func TestAnalyzeOrderMachine(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "order").
+		AddStates("created", "paid", "cancelled", "finished", "orphan", "stuck").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddTransition("created", "cancelled").
+		AddTransition("paid", "finished").
+		AddTransition("paid", "stuck").
+		AddTransition("orphan", "finished").
+		AddFinalStates("finished", "cancelled")
+	codes := findingCodes(f.Analyze())
+	if codes[fsm.FindingUnreachableState] != fsm.SeverityWarning {
+		t.Errorf("orphan is not reported unreachable: %v", f.Analyze())
+	}
+	if codes[fsm.FindingDeadEnd] != fsm.SeverityError {
+		t.Errorf("stuck is not reported as dead end: %v", f.Analyze())
+	}
+}
+
+func TestAnalyzeTerminalTag(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "t").AddStates("a", "b", "c").
+		SetInitial("a").
+		AddTransition("a", "b").
+		AddTransition("a", "c").
+		AddFinalStates("b").
+		SetStateMeta("c", fsm.StateMeta{Tags: []string{fsm.TagTerminal}})
+	if err := f.Validate(); err != nil {
+		t.Errorf("state tagged terminal is not taken as final: %v", err)
+	}
+}
@@ -0,0 +1,137 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"runtime"
+	"strings"
+)
+
+// Condition decides whether a transition is allowed, see AddTransitionOn.
+type Condition func(ctx context.Context, state string) (bool, error)
+
+// Guard is a condition with a readable name, used by diagram labels, see AddTransitionGuard.
+// A Condition is a Guard named after its function, e.g. fsm.Condition(order.IsPhysical).
+type Guard interface {
+	Check(ctx context.Context, state string) (bool, error)
+	String() string
+}
+
+func (c Condition) Check(ctx context.Context, state string) (bool, error) {
+	return c(ctx, state)
+}
+
+// String returns the function name, e.g. IsPhysical.
+func (c Condition) String() string {
+	if c == nil {
+		return ""
+	}
+	fullName := runtime.FuncForPC(reflect.ValueOf(c).Pointer()).Name()
+	names := strings.Split(fullName, ".")
+	// method values are suffixed with -fm
+	return strings.TrimSuffix(names[len(names)-1], "-fm")
+}
+
+type namedGuard struct {
+	name      string
+	condition Condition
+}
+
+func (g namedGuard) Check(ctx context.Context, state string) (bool, error) {
+	return g.condition(ctx, state)
+}
+
+func (g namedGuard) String() string {
+	return g.name
+}
+
+// Named gives condition a readable name.
+func Named(name string, condition Condition) Guard {
+	return namedGuard{name: name, condition: condition}
+}
+
+func joinNames(guards []Guard, op string) string {
+	names := make([]string, 0, len(guards))
+	for _, g := range guards {
+		name := g.String()
+		// combined names are wrapped in parentheses
+		if strings.Contains(name, " && ") || strings.Contains(name, " || ") {
+			name = "(" + name + ")"
+		}
+		names = append(names, name)
+	}
+	return strings.Join(names, " "+op+" ")
+}
+
+// check runs guard, a Denial is a plain false answer to the combinators.
+func check(ctx context.Context, guard Guard, state string) (bool, error) {
+	ok, err := guard.Check(ctx, state)
+	return ok && err == nil, err
+}
+
+func isDenial(err error) bool {
+	var denial *Denial
+	return errors.As(err, &denial)
+}
+
+// And and Or are All and Any of two guards, named like `a && (b || c)`.
+func And(a, b Guard) Guard {
+	return All(a, b)
+}
+
+func Or(a, b Guard) Guard {
+	return Any(a, b)
+}
+
+// Not inverts guard, errors are kept and a Denial counts as false.
+func Not(guard Guard) Guard {
+	name := guard.String()
+	if strings.Contains(name, " ") {
+		name = "(" + name + ")"
+	}
+	return Named("!"+name, func(ctx context.Context, state string) (bool, error) {
+		ok, err := check(ctx, guard, state)
+		if err != nil && !isDenial(err) {
+			return false, err
+		}
+		return !ok, nil
+	})
+}
+
+// All passes when every guard passes, it stops at the first one denying or failing and returns its error.
+func All(guards ...Guard) Guard {
+	return Named(joinNames(guards, "&&"), func(ctx context.Context, state string) (bool, error) {
+		for _, g := range guards {
+			if ok, err := check(ctx, g, state); !ok || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+	})
+}
+
+// Any passes when one guard passes, it stops there.
+// Otherwise the first failure is returned, or else the first Denial.
+func Any(guards ...Guard) Guard {
+	return Named(joinNames(guards, "||"), func(ctx context.Context, state string) (bool, error) {
+		var failure, denial error
+		for _, g := range guards {
+			ok, err := check(ctx, g, state)
+			if ok {
+				return true, nil
+			}
+			if isDenial(err) {
+				if denial == nil {
+					denial = err
+				}
+			} else if err != nil && failure == nil {
+				failure = err
+			}
+		}
+		if failure != nil {
+			return false, failure
+		}
+		return false, denial
+	})
+}
@@ -0,0 +1,218 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ReasonConditionNotMet is the reason code of a condition returning false without a Denial.
+const ReasonConditionNotMet = "condition_not_met"
+
+// Denial is an error a condition returns to explain why it denies a transition,
+// e.g. to tell a user why an action is not available. Transit treats it as a rejection, not a failure.
+type Denial struct {
+	Code    string
+	Message string
+	Params  map[string]interface{}
+}
+
+// Deny returns a Denial, params may be nil.
+func Deny(code, message string, params map[string]interface{}) error {
+	return &Denial{Code: code, Message: message, Params: params}
+}
+
+func (d *Denial) Error() string {
+	return fmt.Sprintf("[fsm] denied %s: %s", d.Code, d.Message)
+}
+
+// conditionNotMet is the Denial of a condition returning false without one.
+func conditionNotMet(transition *Transition) *Denial {
+	return &Denial{Code: ReasonConditionNotMet, Message: fmt.Sprintf("transit(%s) condition not met", transition.Key)}
+}
+
+// Evaluation is the outcome of the condition of an outgoing transition.
+type Evaluation struct {
+	To string
+	// Event is the transition label, if any
+	Event   string
+	Allowed bool
+	// Reason is set when the transition is denied, Err when the condition failed
+	Reason *Denial
+	Err    error
+}
+
+type queryOptions struct {
+	parallel bool
+	timeout  time.Duration
+	cache    bool
+	cacheKey string
+}
+
+type QueryOption func(o *queryOptions)
+
+// InParallel evaluates conditions concurrently, a panic can only be recovered with PanicRecover.
+func InParallel() QueryOption {
+	return func(o *queryOptions) {
+		o.parallel = true
+	}
+}
+
+// WithTimeout denies transitions whose condition has not answered within timeout, with Err set to the context error.
+// The condition keeps running in the background, it should give up once its context is done.
+// A panic is raised again on the caller unless the timeout passed, then it is dropped.
+func WithTimeout(timeout time.Duration) QueryOption {
+	return func(o *queryOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithCache reuses the result of a previous query with the same key as long as Version is the same.
+// Conditions read ctx, e.g. the roles of the caller, so key must identify whatever they read from it,
+// like a user id. Results with a timeout are not cached.
+func WithCache(key string) QueryOption {
+	return func(o *queryOptions) {
+		o.cache = true
+		o.cacheKey = key
+	}
+}
+
+// evaluationCache holds results of the current version by cache key.
+type evaluationCache struct {
+	version     uint64
+	evaluations map[string][]Evaluation
+}
+
+func (f *FSM) cachedEvaluations(key string) ([]Evaluation, bool) {
+	if f.evaluationCache == nil || f.evaluationCache.version != f.version {
+		return nil, false
+	}
+	evaluations, ok := f.evaluationCache.evaluations[key]
+	return evaluations, ok
+}
+
+func (f *FSM) cacheEvaluations(key string, evaluations []Evaluation) {
+	if f.evaluationCache == nil || f.evaluationCache.version != f.version {
+		f.evaluationCache = &evaluationCache{version: f.version, evaluations: make(map[string][]Evaluation)}
+	}
+	f.evaluationCache.evaluations[key] = evaluations
+}
+
+// Evaluate runs the conditions of every transition from the current state, in the order transitions are added.
+// Nothing else runs: no action, hook, listener, observer or metric.
+func (f *FSM) Evaluate(ctx context.Context, opts ...QueryOption) []Evaluation {
+	o := &queryOptions{}
+	for _, opt := range opts {
+		opt(o)
+	}
+	if o.cache {
+		if evaluations, ok := f.cachedEvaluations(o.cacheKey); ok {
+			return append([]Evaluation(nil), evaluations...)
+		}
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+
+	transitions := f.getAvailableTransitions(f.GetCurrentState())
+	evaluations := make([]Evaluation, len(transitions))
+	if o.parallel {
+		var wg sync.WaitGroup
+		for i, t := range transitions {
+			wg.Add(1)
+			go func(i int, t *Transition) {
+				defer wg.Done()
+				evaluations[i] = f.evaluateWithin(ctx, t)
+			}(i, t)
+		}
+		wg.Wait()
+	} else {
+		for i, t := range transitions {
+			evaluations[i] = f.evaluateWithin(ctx, t)
+		}
+	}
+
+	if o.cache && ctx.Err() == nil {
+		f.cacheEvaluations(o.cacheKey, evaluations)
+		return append([]Evaluation(nil), evaluations...)
+	}
+	return evaluations
+}
+
+// PermittedStates returns the targets of transitions from the current state whose condition passes,
+// unlike GetAvailableStateNames.
+func (f *FSM) PermittedStates(ctx context.Context, opts ...QueryOption) []string {
+	names := make([]string, 0)
+	for _, e := range f.Evaluate(ctx, opts...) {
+		if e.Allowed {
+			names = append(names, e.To)
+		}
+	}
+	return names
+}
+
+// PermittedEvents returns the labels of permitted transitions, transitions without label are left out.
+func (f *FSM) PermittedEvents(ctx context.Context, opts ...QueryOption) []string {
+	events := make([]string, 0)
+	for _, e := range f.Evaluate(ctx, opts...) {
+		if e.Allowed && e.Event != "" {
+			events = append(events, e.Event)
+		}
+	}
+	return events
+}
+
+// evaluation is the result of a condition run in its own goroutine, or its panic.
+type evaluation struct {
+	e        Evaluation
+	panicked bool
+	value    interface{}
+}
+
+// evaluateWithin stops waiting for the condition once ctx is done.
+// A panic of the condition is raised again on the calling goroutine, unless it comes too late.
+func (f *FSM) evaluateWithin(ctx context.Context, transition *Transition) Evaluation {
+	if ctx.Done() == nil || transition.Condition == nil {
+		return f.evaluate(ctx, transition)
+	}
+	result := make(chan evaluation, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				result <- evaluation{panicked: true, value: r}
+			}
+		}()
+		result <- evaluation{e: f.evaluate(ctx, transition)}
+	}()
+	select {
+	case r := <-result:
+		if r.panicked {
+			panic(r.value)
+		}
+		return r.e
+	case <-ctx.Done():
+		return Evaluation{To: transition.To.Name, Event: transition.Meta.Label, Err: ctx.Err()}
+	}
+}
+
+func (f *FSM) evaluate(ctx context.Context, transition *Transition) Evaluation {
+	e := Evaluation{To: transition.To.Name, Event: transition.Meta.Label, Allowed: true}
+	if transition.Condition == nil {
+		return e
+	}
+	flag, err := f.checkCondition(ctx, transition)
+	var denial *Denial
+	switch {
+	case errors.As(err, &denial):
+		e.Allowed, e.Reason = false, denial
+	case err != nil:
+		e.Allowed, e.Err = false, err
+	case !flag:
+		e.Allowed, e.Reason = false, conditionNotMet(transition)
+	}
+	return e
+}
@@ -0,0 +1,76 @@
+package fsm_test
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPermittedStatesCacheKey(t *testing.T) {
+	calls := 0
+	adminOnly := func(ctx context.Context, state string) (bool, error) {
+		calls++
+		return fsm.HasRole("admin").Check(ctx, state)
+	}
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddTransitionOn("created", "cancelled", adminOnly)
+	admin := fsm.WithRoles(context.Background(), "admin")
+	guest := fsm.WithRoles(context.Background(), "guest")
+
+	if got := f.PermittedStates(admin, fsm.WithCache("alice")); !reflect.DeepEqual(got, []string{"paid", "cancelled"}) {
+		t.Errorf("admin permitted %v", got)
+	}
+	if got := f.PermittedStates(guest, fsm.WithCache("bob")); !reflect.DeepEqual(got, []string{"paid"}) {
+		t.Errorf("guest permitted %v, the admin result leaked", got)
+	}
+	if got := f.PermittedStates(admin, fsm.WithCache("alice")); !reflect.DeepEqual(got, []string{"paid", "cancelled"}) {
+		t.Errorf("cached admin permitted %v", got)
+	}
+	if calls != 2 {
+		t.Errorf("condition ran %d times, want once per key", calls)
+	}
+
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+	if got := f.PermittedStates(admin, fsm.WithCache("alice")); len(got) != 0 {
+		t.Errorf("stale cache after the state changed: %v", got)
+	}
+}
+
+func TestEvaluateTimeoutPanic(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", func(ctx context.Context, state string) (bool, error) {
+			panic("boom")
+		})
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want boom", r)
+		}
+	}()
+	f.Evaluate(context.Background(), fsm.WithTimeout(time.Second))
+	t.Error("panic did not reach the caller")
+}
+
+func TestEvaluateTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", func(ctx context.Context, state string) (bool, error) {
+			<-release
+			return true, nil
+		}).
+		AddTransitionOn("created", "cancelled", func(ctx context.Context, state string) (bool, error) {
+			return true, nil
+		})
+	evaluations := f.Evaluate(context.Background(), fsm.InParallel(), fsm.WithTimeout(10*time.Millisecond))
+	if len(evaluations) != 2 || evaluations[0].Allowed || evaluations[0].Err != context.DeadlineExceeded || !evaluations[1].Allowed {
+		t.Errorf("evaluations %+v", evaluations)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 )
 
 type FSM struct {
@@ -13,12 +14,31 @@ type FSM struct {
 	transitions     []*Transition
 	globalEnterHook func(ctx context.Context, state string)
 	globalExitHook  func(ctx context.Context, state string)
+	initialState    *State
 	currentState    *State
 	ctx             context.Context
+	// kept for Analyze
+	skippedTransitions []*Transition
+	unknownHooks       []hookRef
+	logger             Logger
+	logLevel           LogLevel
+	done               chan struct{}
+	doneClosed         bool
+	observers          []Observer
+	conditionOverride  ConditionOverride
+	bus                *Bus
+	middleware         []Middleware
+	metrics            Metrics
+	tracer             Tracer
+	panicPolicy        PanicPolicy
+	errorState         *State
+	// version counts state changes, see Version
+	version         uint64
+	evaluationCache *evaluationCache
 }
 
 func NewFSM(ctx context.Context, name string) *FSM {
-	return &FSM{ctx: ctx, name: name}
+	return &FSM{ctx: ctx, name: name, done: make(chan struct{}), bus: NewBus()}
 }
 
 // build fsm
@@ -81,6 +101,20 @@ func (f *FSM) AddTransition(from, to string) *FSM {
 }
 
 func (f *FSM) AddTransitionOn(from, to string, condition func(ctx context.Context, state string) (bool, error)) *FSM {
+	return f.addTransition(from, to, condition, "")
+}
+
+// AddTransitionGuard adds a transition on guard, diagrams label it with the guard name.
+func (f *FSM) AddTransitionGuard(from, to string, guard Guard) *FSM {
+	// a plain Condition keeps its function so Analyze can tell duplicates
+	condition, ok := guard.(Condition)
+	if !ok {
+		condition = guard.Check
+	}
+	return f.addTransition(from, to, condition, guard.String())
+}
+
+func (f *FSM) addTransition(from, to string, condition func(ctx context.Context, state string) (bool, error), conditionName string) *FSM {
 	if !f.hasState(from) {
 		log.Fatalf("\t[fsm] state not defined %s", from)
 		return nil
@@ -89,15 +123,96 @@ func (f *FSM) AddTransitionOn(from, to string, condition func(ctx context.Contex
 		log.Fatalf("\t[fsm] state not defined %s", to)
 		return nil
 	}
+	if f.getState(from).final {
+		log.Fatalf("\t[fsm] can not add transition from final state %s", from)
+		return nil
+	}
+	transition := NewTransition(f.getState(from), f.getState(to), condition)
+	transition.ConditionName = conditionName
 	if !f.hasTransition(from, to) {
-		fromState := f.getState(from)
-		toState := f.getState(to)
-		f.transitions = append(f.transitions, NewTransition(fromState, toState, condition))
+		f.transitions = append(f.transitions, transition)
 	} else {
-		log.Printf("\t[fsm] Skipped add transition due to transition exists. from %s to %s\n",
-			from, to)
+		f.log(LogLevelWarn, "skipped add transition due to transition exists", "from", from, "to", to)
+		f.skippedTransitions = append(f.skippedTransitions, transition)
+	}
+
+	return f
+}
+
+// SetInitial declares the state an instance starts in,
+// the current state is set to it (without hooks) if not set yet.
+func (f *FSM) SetInitial(state string) *FSM {
+	s := f.getState(state)
+	if s == nil {
+		log.Fatalf("\t[fsm] state not defined %s", state)
+		return nil
 	}
+	f.initialState = s
+	if f.currentState == nil {
+		f.currentState = s
+		f.version++
+		f.moveInstance("", s.Name)
+	}
+	return f
+}
 
+// AddFinalStates declares states that complete the fsm, they can not have outgoing transitions.
+func (f *FSM) AddFinalStates(state ...string) *FSM {
+	for _, name := range state {
+		s := f.getState(name)
+		if s == nil {
+			log.Fatalf("\t[fsm] state not defined %s", name)
+			return nil
+		}
+		if len(f.getAvailableTransitions(name)) > 0 {
+			log.Fatalf("\t[fsm] final state %s can not have outgoing transitions", name)
+			return nil
+		}
+		s.final = true
+	}
+	return f
+}
+
+// AddTransitionAction attaches behaviour to the transition from -> to rather than to a state,
+// e.g. refund on paid -> cancelled only. Actions run after the exit hooks of from,
+// an action error skips the enter hooks of to and leaves the state unchanged.
+func (f *FSM) AddTransitionAction(from, to string, action func(ctx context.Context, from, to string) error) *FSM {
+	t := f.getTransition(from, to)
+	if t == nil {
+		log.Fatalf("\t[fsm] transition not defined from %s to %s", from, to)
+		return nil
+	}
+	t.Actions = append(t.Actions, action)
+	return f
+}
+
+func (f *FSM) SetStateMeta(state string, meta StateMeta) *FSM {
+	s := f.getState(state)
+	if s == nil {
+		log.Fatalf("\t[fsm] state not defined %s", state)
+		return nil
+	}
+	s.Meta = meta
+	return f
+}
+
+func (f *FSM) SetTransitionMeta(from, to string, meta TransitionMeta) *FSM {
+	t := f.getTransition(from, to)
+	if t == nil {
+		log.Fatalf("\t[fsm] transition not defined from %s to %s", from, to)
+		return nil
+	}
+	t.Meta = meta
+	return f
+}
+
+// ConditionOverride decides conditions instead of the conditions themselves, evaluate runs the real one.
+type ConditionOverride func(ctx context.Context, transition *Transition, evaluate func() (bool, error)) (bool, error)
+
+// SetConditionOverride is meant for simulation and testing, nil restores the real conditions.
+// Transitions without condition are not affected.
+func (f *FSM) SetConditionOverride(override ConditionOverride) *FSM {
+	f.conditionOverride = override
 	return f
 }
 
@@ -109,63 +224,226 @@ func (f *FSM) SetState(state string) error {
 	s := f.getState(state)
 	if s == nil {
 		err := errors.New(fmt.Sprintf("\t[fsm] state not defined %s", state))
-		log.Print(err)
+		f.log(LogLevelError, "set state failed", "to", state, "error", err)
 		return err
 	}
-	log.Printf("\t[fsm] set status to %s\n", state)
-	f.setState(s)
-	return nil
+	f.log(LogLevelInfo, "set state", "from", f.GetCurrentState(), "to", state)
+	ctx := withForced(f.ctx)
+	err := f.setState(ctx, s, nil)
+	if err != nil {
+		f.log(LogLevelError, "set state failed", "to", state, "error", err)
+		f.enterErrorState(ctx, err)
+	}
+	return err
 }
 
 // transit from current state to the given state
 func (f *FSM) Transit(state string) error {
+	return f.transit(f.ctx, state)
+}
+
+func (f *FSM) transit(ctx context.Context, state string) error {
+	ctx, span := f.startTransitSpan(ctx, state)
+	err := f.tryTransit(ctx, state)
+	f.enterErrorState(ctx, err)
+	endSpan(span, err)
+	return err
+}
+
+func (f *FSM) tryTransit(ctx context.Context, state string) error {
+	if f.currentState == nil {
+		err := errors.New(fmt.Sprintf("\t[fsm] current state not set, transit to %s", state))
+		f.log(LogLevelWarn, "transit rejected", "to", state, "error", err)
+		f.notifyReject(ctx, "", state, err)
+		return err
+	}
 	availableTransitions := f.getAvailableTransitions(f.currentState.Name)
 	for _, transition := range availableTransitions {
 		if transition.To.Name == state {
-			log.Printf("\t[fsm] transit status to %s\n", state)
-			return f.doTransit(transition)
+			start := time.Now()
+			reached := false
+			err := f.chain(func(ctx context.Context, transition *Transition) error {
+				reached = true
+				return f.doTransit(ctx, transition)
+			})(ctx, transition)
+			if err != nil {
+				if !reached {
+					f.log(LogLevelWarn, "transit rejected by middleware", transitionArgs(transition, "error", err)...)
+					f.notifyReject(ctx, transition.From.Name, transition.To.Name, err)
+					f.countTransition(transition.From.Name, transition.To.Name, OutcomeRejected)
+				}
+				return err
+			}
+			f.log(LogLevelInfo, "transited", transitionArgs(transition, "duration", time.Since(start))...)
+			return nil
 		}
 	}
 	err := errors.New(fmt.Sprintf("\t[fsm] transition from %s to %s not found", f.currentState.Name, state))
-	log.Println(err.Error())
+	f.log(LogLevelWarn, "transit rejected", "from", f.currentState.Name, "to", state, "error", err)
+	// not counted, the target is caller input and would make metric labels unbounded
+	f.notifyReject(ctx, f.currentState.Name, state, err)
 	return err
 }
 
 // check condition and set state
-func (f *FSM) doTransit(transition *Transition) error {
-	log.Printf("\t[fsm] start condition check for transit(%s)\n", transition.Key)
+func (f *FSM) doTransit(ctx context.Context, transition *Transition) error {
 	if transition.Condition != nil {
-		if flag, err := transition.Condition(f.ctx, f.GetCurrentState()); err != nil {
-			log.Printf("\t[fsm] transit(%s) condition check err %s\n", transition.Key, err)
+		f.log(LogLevelDebug, "start condition check", transitionArgs(transition)...)
+		guardCtx, span := f.startGuardSpan(ctx, transition)
+		start := time.Now()
+		flag, err := f.checkCondition(guardCtx, transition)
+		f.observeGuard(transition, start)
+		endSpan(span, err)
+		f.notifyGuard(ctx, transition, flag, err)
+		var denial *Denial
+		if errors.As(err, &denial) {
+			f.log(LogLevelWarn, "transit rejected", transitionArgs(transition, "error", err)...)
+			f.notifyReject(ctx, transition.From.Name, transition.To.Name, err)
+			f.countTransition(transition.From.Name, transition.To.Name, OutcomeRejected)
+			return err
+		} else if err != nil {
+			f.log(LogLevelWarn, "transit rejected", transitionArgs(transition, "error", err)...)
+			f.notifyReject(ctx, transition.From.Name, transition.To.Name, err)
+			f.countTransition(transition.From.Name, transition.To.Name, OutcomeError)
 			return err
 		} else if flag == false {
-			err = errors.New(fmt.Sprintf("[fsm] transit(%s) condition not met", transition.Key))
-			log.Printf("\t[fsm] transit(%s) condition check err %s\n", transition.Key, err)
+			// a plain false answer is a rejection like a Denial, not a failure
+			err = conditionNotMet(transition)
+			f.log(LogLevelWarn, "transit rejected", transitionArgs(transition, "error", err)...)
+			f.notifyReject(ctx, transition.From.Name, transition.To.Name, err)
+			f.countTransition(transition.From.Name, transition.To.Name, OutcomeRejected)
 			return err
 		}
 	} else {
-		log.Printf("\t[fsm] skipped condition check for transit(%s) due to condition is nil\n", transition.Key)
+		f.log(LogLevelDebug, "skipped condition check due to condition is nil", transitionArgs(transition)...)
 	}
 
-	f.setState(transition.To)
+	if err := f.setState(ctx, transition.To, transition); err != nil {
+		f.log(LogLevelError, "transit failed", transitionArgs(transition, "error", err)...)
+		f.countTransition(transition.From.Name, transition.To.Name, OutcomeError)
+		return err
+	}
+	f.countTransition(transition.From.Name, transition.To.Name, OutcomeOK)
 	return nil
 }
 
-// setState will execute state hooks and global hooks
-func (f *FSM) setState(state *State) {
-	f.executeGlobalEnterHook(state)
-	f.executeEnterHook(state)
+// checkCondition runs the condition of transition, honouring the condition override and panic policy.
+func (f *FSM) checkCondition(ctx context.Context, transition *Transition) (bool, error) {
+	evaluate := func() (bool, error) {
+		return transition.Condition(ctx, transition.From.Name)
+	}
+	var flag bool
+	var err error
+	panicErr := f.protect(transition.To, PanicCondition, func() {
+		if f.conditionOverride != nil {
+			flag, err = f.conditionOverride(ctx, transition, evaluate)
+		} else {
+			flag, err = evaluate()
+		}
+	})
+	if panicErr != nil {
+		return false, panicErr
+	}
+	return flag, err
+}
+
+// setState leaves the current state and enters state, in order:
+// exit hooks and the global exit hook of the current state, actions of transition if any,
+// the global enter hook and enter hooks of state, then the current state changes.
+// It only returns action errors and recovered panics, see SetPanicPolicy,
+// on error the remaining steps are skipped and the current state is left as it was.
+func (f *FSM) setState(ctx context.Context, state *State, transition *Transition) error {
+	from := f.GetCurrentState()
+	err := f.exitState(ctx)
+	if err == nil && transition != nil {
+		err = f.executeActions(ctx, transition)
+	}
+	if err == nil {
+		err = f.enterState(ctx, from, state)
+	}
+	if err != nil {
+		f.notifyReject(ctx, from, state.Name, err)
+	}
+	return err
+}
+
+// exitState runs the exit hooks of the current state, if any.
+func (f *FSM) exitState(ctx context.Context) error {
+	if f.currentState == nil {
+		return nil
+	}
+	if err := f.executeExitHooks(ctx, f.currentState); err != nil {
+		return err
+	}
+	return f.executeGlobalExitHook(ctx, f.currentState)
+}
+
+// enterState runs the enter hooks of state and makes it the current state.
+func (f *FSM) enterState(ctx context.Context, from string, state *State) error {
+	if err := f.executeGlobalEnterHook(ctx, state); err != nil {
+		return err
+	}
+	if err := f.executeEnterHooks(ctx, from, state); err != nil {
+		return err
+	}
 	f.currentState = state
-	f.executeExitHook(state)
-	f.executeGlobalExitHook(state)
+	f.version++
+	f.moveInstance(from, state.Name)
+	f.notifyTransit(ctx, from, state.Name)
+	f.publishTransition(ctx, from, state.Name)
+	if state.final && !f.doneClosed {
+		f.doneClosed = true
+		close(f.done)
+	}
+	return nil
 }
 
 /***** retrieve fsm  *****/
 
+func (f *FSM) Name() string {
+	return f.name
+}
+
+// States returns all states in the order they are added.
+func (f *FSM) States() []*State {
+	return append([]*State(nil), f.states...)
+}
+
+// Transitions returns all transitions in the order they are added.
+func (f *FSM) Transitions() []*Transition {
+	return append([]*Transition(nil), f.transitions...)
+}
+
+// GetCurrentState returns empty string if neither initial nor current state is set.
 func (f *FSM) GetCurrentState() string {
+	if f.currentState == nil {
+		return ""
+	}
 	return f.currentState.Name
 }
 
+func (f *FSM) GetInitialState() string {
+	if f.initialState == nil {
+		return ""
+	}
+	return f.initialState.Name
+}
+
+// Version changes every time the current state is set, e.g. to cache what depends on it.
+func (f *FSM) Version() uint64 {
+	return f.version
+}
+
+// IsFinal reports whether the current state is a final state.
+func (f *FSM) IsFinal() bool {
+	return f.currentState != nil && f.currentState.final
+}
+
+// Done is closed once a final state is entered.
+func (f *FSM) Done() <-chan struct{} {
+	return f.done
+}
+
 func (f *FSM) GetAvailableStateNames() []string {
 	state := f.GetCurrentState()
 	states := f.getAvailableStates(state)
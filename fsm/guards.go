@@ -0,0 +1,309 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// reason codes of the built-in conditions
+const (
+	ReasonField      = "field"
+	ReasonTimeWindow = "time_window"
+	ReasonCounter    = "counter"
+	ReasonRole       = "role"
+)
+
+type payloadKey struct{}
+
+type rolesKey struct{}
+
+type clockKey struct{}
+
+// WithPayload attaches data to ctx for the field conditions, e.g. the order an instance belongs to.
+func WithPayload(ctx context.Context, payload interface{}) context.Context {
+	return context.WithValue(ctx, payloadKey{}, payload)
+}
+
+func Payload(ctx context.Context) interface{} {
+	return ctx.Value(payloadKey{})
+}
+
+// WithRoles attaches the roles of the caller to ctx for HasRole.
+func WithRoles(ctx context.Context, roles ...string) context.Context {
+	return context.WithValue(ctx, rolesKey{}, roles)
+}
+
+func Roles(ctx context.Context) []string {
+	roles, _ := ctx.Value(rolesKey{}).([]string)
+	return roles
+}
+
+// WithClock makes the time conditions read the time from now, e.g. the Now method of fsmtest.FakeClock.
+func WithClock(ctx context.Context, now func() time.Time) context.Context {
+	return context.WithValue(ctx, clockKey{}, now)
+}
+
+// Now returns the time of the clock attached with WithClock, or else time.Now().
+func Now(ctx context.Context) time.Time {
+	if now, ok := ctx.Value(clockKey{}).(func() time.Time); ok {
+		return now()
+	}
+	return time.Now()
+}
+
+/***** payload fields *****/
+
+// Field compares a field of the payload with value, op is one of == != < <= > >=.
+// path is a key of a map with string keys or an exported struct field, dotted to go deeper, e.g. "Shipping.Country".
+// Numbers compare across types, strings in lexical order, anything else only with == and !=.
+func Field(path, op string, value interface{}) Guard {
+	name := fmt.Sprintf("%s %s %#v", path, op, value)
+	return Named(name, func(ctx context.Context, state string) (bool, error) {
+		actual, err := lookupField(Payload(ctx), path)
+		if err != nil {
+			return false, err
+		}
+		ok, err := compare(actual, op, value)
+		if err != nil {
+			return false, err
+		}
+		if !ok {
+			return false, Deny(ReasonField, fmt.Sprintf("%s is %v, want %s", path, actual, name),
+				map[string]interface{}{"field": path, "op": op, "value": value, "actual": actual})
+		}
+		return true, nil
+	})
+}
+
+func FieldEquals(path string, value interface{}) Guard {
+	return Field(path, "==", value)
+}
+
+func lookupField(payload interface{}, path string) (interface{}, error) {
+	v := reflect.ValueOf(payload)
+	for _, part := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			// string kinds only, e.g. map[OrderField]interface{}
+			keyType := v.Type().Key()
+			if keyType.Kind() != reflect.String {
+				return nil, errors.New(fmt.Sprintf("[fsm] payload field %s is in a map with %s keys", path, keyType))
+			}
+			v = v.MapIndex(reflect.ValueOf(part).Convert(keyType))
+		case reflect.Struct:
+			field, ok := v.Type().FieldByName(part)
+			if !ok {
+				return nil, errors.New(fmt.Sprintf("[fsm] payload field not found %s", path))
+			}
+			// walk promoted fields one embedded struct at a time, embedded pointers may be nil
+			for i, index := range field.Index {
+				if i > 0 && v.Kind() == reflect.Ptr {
+					if v.IsNil() {
+						return nil, errors.New(fmt.Sprintf("[fsm] payload field %s is in a nil embedded struct", path))
+					}
+					v = v.Elem()
+				}
+				v = v.Field(index)
+			}
+		default:
+			v = reflect.Value{}
+		}
+		if !v.IsValid() {
+			return nil, errors.New(fmt.Sprintf("[fsm] payload field not found %s", path))
+		}
+		if !v.CanInterface() {
+			return nil, errors.New(fmt.Sprintf("[fsm] payload field %s is not exported", path))
+		}
+	}
+	return v.Interface(), nil
+}
+
+func compare(actual interface{}, op string, value interface{}) (bool, error) {
+	var c int
+	a, aNumber := toFloat(actual)
+	b, bNumber := toFloat(value)
+	as, aString := actual.(string)
+	bs, bString := value.(string)
+	switch {
+	case aNumber && bNumber:
+		c = compareOrdered(a < b, a > b)
+	case aString && bString:
+		c = compareOrdered(as < bs, as > bs)
+	case op == "==":
+		return reflect.DeepEqual(actual, value), nil
+	case op == "!=":
+		return !reflect.DeepEqual(actual, value), nil
+	default:
+		return false, errors.New(fmt.Sprintf("[fsm] can not compare %T %s %T", actual, op, value))
+	}
+	switch op {
+	case "==":
+		return c == 0, nil
+	case "!=":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return false, errors.New(fmt.Sprintf("[fsm] unknown operator %s", op))
+}
+
+func compareOrdered(less, greater bool) int {
+	if less {
+		return -1
+	}
+	if greater {
+		return 1
+	}
+	return 0
+}
+
+func toFloat(i interface{}) (float64, bool) {
+	v := reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+/***** time windows *****/
+
+// Between passes from start until end, a zero start or end leaves that side open.
+// Time conditions read the time from ctx, see WithClock.
+func Between(start, end time.Time) Guard {
+	name := fmt.Sprintf("now in [%s, %s)", formatTime(start), formatTime(end))
+	return Named(name, func(ctx context.Context, state string) (bool, error) {
+		now := Now(ctx)
+		if (!start.IsZero() && now.Before(start)) || (!end.IsZero() && !now.Before(end)) {
+			return false, Deny(ReasonTimeWindow, fmt.Sprintf("%s is not in [%s, %s)", now.Format(time.RFC3339), formatTime(start), formatTime(end)),
+				map[string]interface{}{"start": start, "end": end})
+		}
+		return true, nil
+	})
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// Daily passes between two times of day in loc, e.g. Daily(9*time.Hour, 18*time.Hour, loc),
+// a window like Daily(22*time.Hour, 6*time.Hour, loc) spans midnight. A nil loc is time.Local.
+func Daily(from, to time.Duration, loc *time.Location) Guard {
+	if loc == nil {
+		loc = time.Local
+	}
+	name := fmt.Sprintf("daily %s-%s", formatClock(from), formatClock(to))
+	return Named(name, func(ctx context.Context, state string) (bool, error) {
+		now := Now(ctx).In(loc)
+		midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+		since := now.Sub(midnight)
+		in := since >= from && since < to
+		if from > to {
+			in = since >= from || since < to
+		}
+		if !in {
+			return false, Deny(ReasonTimeWindow, fmt.Sprintf("%s is outside %s", now.Format("15:04"), name),
+				map[string]interface{}{"from": formatClock(from), "to": formatClock(to)})
+		}
+		return true, nil
+	})
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+/***** counters *****/
+
+// Counter counts anything a guard should limit, e.g. retries.
+// Count is an action for AddTransitionAction, so a transition can count itself.
+type Counter struct {
+	name  string
+	mu    sync.Mutex
+	value int
+}
+
+func NewCounter(name string) *Counter {
+	return &Counter{name: name}
+}
+
+func (c *Counter) Add(delta int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value += delta
+}
+
+func (c *Counter) Value() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.value
+}
+
+func (c *Counter) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.value = 0
+}
+
+func (c *Counter) Count(ctx context.Context, from, to string) error {
+	c.Add(1)
+	return nil
+}
+
+// Below passes while the counter is less than max.
+func (c *Counter) Below(max int) Guard {
+	return c.condition("<", max, func(value int) bool { return value < max })
+}
+
+// AtLeast passes once the counter reached min.
+func (c *Counter) AtLeast(min int) Guard {
+	return c.condition(">=", min, func(value int) bool { return value >= min })
+}
+
+func (c *Counter) condition(op string, limit int, pass func(value int) bool) Guard {
+	name := fmt.Sprintf("%s %s %d", c.name, op, limit)
+	return Named(name, func(ctx context.Context, state string) (bool, error) {
+		if value := c.Value(); !pass(value) {
+			return false, Deny(ReasonCounter, fmt.Sprintf("%s is %d, want %s", c.name, value, name),
+				map[string]interface{}{"counter": c.name, "value": value, "limit": limit})
+		}
+		return true, nil
+	})
+}
+
+/***** roles *****/
+
+// HasRole passes when the caller has one of roles, see WithRoles.
+func HasRole(roles ...string) Guard {
+	name := "role in (" + strings.Join(roles, ", ") + ")"
+	return Named(name, func(ctx context.Context, state string) (bool, error) {
+		for _, role := range Roles(ctx) {
+			if matchAny(roles, role) {
+				return true, nil
+			}
+		}
+		return false, Deny(ReasonRole, fmt.Sprintf("requires %s", strings.Join(roles, " or ")),
+			map[string]interface{}{"roles": roles})
+	})
+}
@@ -0,0 +1,246 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/fsmtest"
+	"testing"
+	"time"
+)
+
+type orderField string
+
+type shipping struct {
+	Country string
+	zip     string
+}
+
+type Base struct {
+	Tenant string
+}
+
+type order struct {
+	*Base
+	Amount   int
+	Shipping shipping
+	Extra    map[orderField]interface{}
+	Codes    map[int]string
+}
+
+func isPhysical(ctx context.Context, state string) (bool, error) {
+	return true, nil
+}
+
+func TestFieldLookup(t *testing.T) {
+	ctx := fsm.WithPayload(context.Background(), &order{
+		Amount:   80,
+		Shipping: shipping{Country: "FR", zip: "75001"},
+		Extra:    map[orderField]interface{}{"gift": true},
+		Codes:    map[int]string{1: "a"},
+	})
+	tests := []struct {
+		guard   fsm.Guard
+		allowed bool
+		failed  bool
+	}{
+		{fsm.Field("Amount", "<", 100), true, false},
+		{fsm.Field("Amount", ">=", 100.5), false, false},
+		{fsm.FieldEquals("Shipping.Country", "FR"), true, false},
+		{fsm.FieldEquals("Extra.gift", true), true, false},
+		{fsm.FieldEquals("Extra.missing", true), false, true},
+		{fsm.FieldEquals("Shipping.zip", "75001"), false, true},
+		{fsm.FieldEquals("Codes.1", "a"), false, true},
+		{fsm.FieldEquals("Tenant", "acme"), false, true},
+		{fsm.FieldEquals("Missing", "x"), false, true},
+	}
+	for _, tt := range tests {
+		ok, err := tt.guard.Check(ctx, "created")
+		var denial *fsm.Denial
+		if failed := err != nil && !errors.As(err, &denial); ok != tt.allowed || failed != tt.failed {
+			t.Errorf("%s = %v, %v", tt.guard, ok, err)
+		}
+	}
+}
+
+func TestFieldLookupEmbedded(t *testing.T) {
+	guard := fsm.FieldEquals("Tenant", "acme")
+	ok, err := guard.Check(fsm.WithPayload(context.Background(), &order{Base: &Base{Tenant: "acme"}}), "created")
+	if !ok || err != nil {
+		t.Errorf("embedded field answers %v, %v", ok, err)
+	}
+	ok, err = guard.Check(fsm.WithPayload(context.Background(), order{Amount: 1}), "created")
+	if ok || err == nil {
+		t.Errorf("nil embedded struct answers %v, %v", ok, err)
+	}
+}
+
+func denialCode(err error) string {
+	var denial *fsm.Denial
+	if errors.As(err, &denial) {
+		return denial.Code
+	}
+	return ""
+}
+
+func TestBetween(t *testing.T) {
+	clock := fsmtest.NewFakeClock(time.Date(2022, 9, 1, 12, 0, 0, 0, time.UTC))
+	ctx := fsm.WithClock(context.Background(), clock.Now)
+	start := time.Date(2022, 9, 1, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2022, 9, 1, 18, 0, 0, 0, time.UTC)
+	tests := []struct {
+		guard   fsm.Guard
+		now     time.Time
+		allowed bool
+	}{
+		{fsm.Between(start, end), start.Add(-time.Second), false},
+		{fsm.Between(start, end), start, true},
+		{fsm.Between(start, end), end.Add(-time.Second), true},
+		{fsm.Between(start, end), end, false},
+		{fsm.Between(time.Time{}, end), start.Add(-time.Hour), true},
+		{fsm.Between(start, time.Time{}), end.Add(time.Hour), true},
+	}
+	for _, tt := range tests {
+		clock.Set(tt.now)
+		ok, err := tt.guard.Check(ctx, "created")
+		if ok != tt.allowed || (!ok && denialCode(err) != fsm.ReasonTimeWindow) {
+			t.Errorf("%s at %s = %v, %v", tt.guard, tt.now.Format(time.RFC3339), ok, err)
+		}
+	}
+}
+
+func TestDaily(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	clock := fsmtest.NewFakeClock(time.Time{})
+	ctx := fsm.WithClock(context.Background(), clock.Now)
+	at := func(hour, minute int) time.Time {
+		// the clock may be in another zone than the window
+		return time.Date(2022, 9, 1, hour, minute, 0, 0, loc).UTC()
+	}
+	office := fsm.Daily(9*time.Hour, 18*time.Hour, loc)
+	night := fsm.Daily(22*time.Hour, 6*time.Hour, loc)
+	tests := []struct {
+		guard   fsm.Guard
+		now     time.Time
+		allowed bool
+	}{
+		{office, at(8, 59), false},
+		{office, at(9, 0), true},
+		{office, at(17, 59), true},
+		{office, at(18, 0), false},
+		{night, at(21, 59), false},
+		{night, at(22, 0), true},
+		{night, at(23, 59), true},
+		{night, at(0, 0), true},
+		{night, at(5, 59), true},
+		{night, at(6, 0), false},
+		{night, at(12, 0), false},
+	}
+	for _, tt := range tests {
+		clock.Set(tt.now)
+		ok, err := tt.guard.Check(ctx, "created")
+		if ok != tt.allowed || (!ok && denialCode(err) != fsm.ReasonTimeWindow) {
+			t.Errorf("%s at %s = %v, %v", tt.guard, tt.now.In(loc).Format("15:04"), ok, err)
+		}
+	}
+}
+
+func TestCounter(t *testing.T) {
+	retries := fsm.NewCounter("retries")
+	f := fsm.NewFSM(context.Background(), "payment").AddStates("failed", "pending").
+		SetInitial("failed").
+		AddTransitionGuard("failed", "pending", retries.Below(2)).
+		AddTransition("pending", "failed").
+		AddTransitionAction("failed", "pending", retries.Count)
+	for i := 0; i < 2; i++ {
+		if err := f.Transit("pending"); err != nil {
+			t.Fatalf("retry %d: %v", i, err)
+		}
+		if err := f.Transit("failed"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	err := f.Transit("pending")
+	var denial *fsm.Denial
+	if !errors.As(err, &denial) || denial.Code != fsm.ReasonCounter || denial.Params["value"] != 2 {
+		t.Errorf("third retry returns %v", err)
+	}
+	if retries.Value() != 2 {
+		t.Errorf("counter %d, want 2", retries.Value())
+	}
+
+	atLeast := retries.AtLeast(2)
+	if ok, err := atLeast.Check(context.Background(), "failed"); !ok || err != nil {
+		t.Errorf("AtLeast(2) at 2 = %v, %v", ok, err)
+	}
+	retries.Reset()
+	if ok, err := atLeast.Check(context.Background(), "failed"); ok || denialCode(err) != fsm.ReasonCounter {
+		t.Errorf("AtLeast(2) after Reset = %v, %v", ok, err)
+	}
+	if ok, _ := retries.Below(2).Check(context.Background(), "failed"); !ok {
+		t.Error("Below(2) after Reset denies")
+	}
+}
+
+func TestHasRole(t *testing.T) {
+	guard := fsm.HasRole("admin", "support")
+	tests := []struct {
+		roles   []string
+		allowed bool
+	}{
+		{[]string{"admin"}, true},
+		{[]string{"guest", "support"}, true},
+		{[]string{"guest"}, false},
+		{nil, false},
+	}
+	for _, tt := range tests {
+		ok, err := guard.Check(fsm.WithRoles(context.Background(), tt.roles...), "created")
+		if ok != tt.allowed || (!ok && denialCode(err) != fsm.ReasonRole) {
+			t.Errorf("roles %v = %v, %v", tt.roles, ok, err)
+		}
+	}
+}
+
+func TestGuardNames(t *testing.T) {
+	tests := []struct {
+		guard fsm.Guard
+		name  string
+	}{
+		{fsm.Condition(isPhysical), "isPhysical"},
+		{fsm.Named("paid in full", isPhysical), "paid in full"},
+		{fsm.And(fsm.Condition(isPhysical), fsm.Not(fsm.FieldEquals("Type", "virtual"))), `isPhysical && !(Type == "virtual")`},
+		{fsm.All(fsm.Or(fsm.HasRole("admin"), fsm.Field("Amount", "<", 100)), fsm.Condition(isPhysical)),
+			`(role in (admin) || Amount < 100) && isPhysical`},
+		{fsm.NewCounter("retries").Below(3), "retries < 3"},
+	}
+	for _, tt := range tests {
+		if got := tt.guard.String(); got != tt.name {
+			t.Errorf("name %q, want %q", got, tt.name)
+		}
+	}
+}
+
+func newGuardedOrder(ctx context.Context) *fsm.FSM {
+	return fsm.NewFSM(ctx, "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransitionGuard("created", "paid", fsm.Any(fsm.HasRole("admin"), fsm.Field("Amount", "<", 100))).
+		AddTransitionOn("created", "cancelled", isPhysical)
+}
+
+func TestAddTransitionGuard(t *testing.T) {
+	names := make(map[string]string)
+	for _, transition := range newGuardedOrder(context.Background()).Transitions() {
+		names[transition.To.Name] = transition.ConditionName
+	}
+	if names["paid"] != "role in (admin) || Amount < 100" || names["cancelled"] != "" {
+		t.Errorf("condition names %v", names)
+	}
+
+	guest := fsm.WithRoles(context.Background(), "guest")
+	if err := newGuardedOrder(fsm.WithPayload(guest, order{Amount: 200})).Transit("paid"); err == nil {
+		t.Error("guest transits a large order")
+	}
+	if err := newGuardedOrder(fsm.WithPayload(guest, order{Amount: 20})).Transit("paid"); err != nil {
+		t.Error(err)
+	}
+}
@@ -2,18 +2,98 @@ package fsm
 
 import (
 	"context"
-	"fmt"
+	"time"
 )
 
-func (f *FSM) AddStateEnterHook(state string, hook func(ctx context.Context, state string)) *FSM {
+// Hook is a state hook, lower Priority runs first, equal priorities run in the order added.
+// A named hook can be removed, FromStates limits an enter hook to transitions from those states.
+type Hook struct {
+	Name       string
+	Priority   int
+	FromStates []string
+	Func       func(ctx context.Context, state string)
+}
+
+type HookOption func(h *Hook)
+
+func NewHook(hook func(ctx context.Context, state string), opts ...HookOption) *Hook {
+	h := &Hook{Func: hook}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WithHookName names a hook so it can be removed with RemoveStateEnterHook or RemoveStateExitHook.
+func WithHookName(name string) HookOption {
+	return func(h *Hook) {
+		h.Name = name
+	}
+}
+
+// WithPriority orders hooks of a state, default is 0 and lower runs first.
+func WithPriority(priority int) HookOption {
+	return func(h *Hook) {
+		h.Priority = priority
+	}
+}
+
+// FromStates runs an enter hook only when the state is entered from one of states, exit hooks ignore it.
+func FromStates(states ...string) HookOption {
+	return func(h *Hook) {
+		h.FromStates = states
+	}
+}
+
+// runsFrom reports whether the hook applies to a transition from the given state.
+func (h *Hook) runsFrom(from string) bool {
+	if len(h.FromStates) == 0 {
+		return true
+	}
+	for _, s := range h.FromStates {
+		if s == from {
+			return true
+		}
+	}
+	return false
+}
+
+// AddStateEnterHook and AddStateExitHook add a hook to the hooks of state,
+// they skip undefined states, Analyze reports them
+func (f *FSM) AddStateEnterHook(state string, hook func(ctx context.Context, state string), opts ...HookOption) *FSM {
 	s := f.getState(state)
-	s.SetEnterHook(hook)
+	if s == nil {
+		f.log(LogLevelWarn, "skipped add enter hook due to state not defined", "state", state)
+		f.unknownHooks = append(f.unknownHooks, hookRef{state: state, kind: HookEnter})
+		return f
+	}
+	s.AddEnterHook(NewHook(hook, opts...))
 	return f
 }
 
-func (f *FSM) AddStateExitHook(state string, hook func(ctx context.Context, state string)) *FSM {
+func (f *FSM) AddStateExitHook(state string, hook func(ctx context.Context, state string), opts ...HookOption) *FSM {
 	s := f.getState(state)
-	s.SetExitHook(hook)
+	if s == nil {
+		f.log(LogLevelWarn, "skipped add exit hook due to state not defined", "state", state)
+		f.unknownHooks = append(f.unknownHooks, hookRef{state: state, kind: HookExit})
+		return f
+	}
+	s.AddExitHook(NewHook(hook, opts...))
+	return f
+}
+
+// RemoveStateEnterHook and RemoveStateExitHook remove hooks added with WithHookName.
+func (f *FSM) RemoveStateEnterHook(state, name string) *FSM {
+	if s := f.getState(state); s != nil {
+		s.RemoveEnterHook(name)
+	}
+	return f
+}
+
+func (f *FSM) RemoveStateExitHook(state, name string) *FSM {
+	if s := f.getState(state); s != nil {
+		s.RemoveExitHook(name)
+	}
 	return f
 }
 
@@ -29,27 +109,71 @@ func (f *FSM) AddGlobalExitHook(hook func(ctx context.Context, state string)) *F
 	return f
 }
 
-func (f *FSM) executeHook(state *State, hook func(ctx context.Context, state string)) {
-	if hook != nil {
-		fmt.Printf("\t[fsm] start execute hook for state %s\n", state.Name)
-		hook(f.ctx, state.Name)
+// executeHook returns the panic of hook if it is recovered, see SetPanicPolicy
+func (f *FSM) executeHook(ctx context.Context, state *State, kind string, hook func(ctx context.Context, state string)) error {
+	if hook == nil {
+		return nil
 	}
+	f.notifyHook(ctx, state, kind)
+	f.log(LogLevelDebug, "start execute hook", "state", state.Name, "hook", kind)
+	hookCtx, span := f.startHookSpan(ctx, state, kind)
+	start := time.Now()
+	err := f.protect(state, kind, func() {
+		hook(hookCtx, state.Name)
+	})
+	f.observeHook(state, kind, start)
+	endSpan(span, err)
+	return err
 }
 
-func (f *FSM) executeGlobalEnterHook(state *State) {
-	if f.globalEnterHook != nil {
-		f.executeHook(state, f.globalEnterHook)
-	}
+func (f *FSM) executeGlobalEnterHook(ctx context.Context, state *State) error {
+	return f.executeHook(ctx, state, HookGlobalEnter, f.globalEnterHook)
+}
+
+func (f *FSM) executeGlobalExitHook(ctx context.Context, state *State) error {
+	return f.executeHook(ctx, state, HookGlobalExit, f.globalExitHook)
 }
 
-func (f *FSM) executeGlobalExitHook(state *State) {
-	f.executeHook(state, f.globalExitHook)
+// executeEnterHooks runs enter hooks of state for a transition from the given state, it stops at the first panic
+func (f *FSM) executeEnterHooks(ctx context.Context, from string, state *State) error {
+	for _, h := range state.enterHooks {
+		if h.runsFrom(from) {
+			if err := f.executeHook(ctx, state, HookEnter, h.Func); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
 }
 
-func (f *FSM) executeEnterHook(state *State) {
-	f.executeHook(state, state.enterHook)
+// executeExitHooks runs exit hooks of state, it stops at the first panic
+func (f *FSM) executeExitHooks(ctx context.Context, state *State) error {
+	for _, h := range state.exitHooks {
+		if err := f.executeHook(ctx, state, HookExit, h.Func); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
-func (f *FSM) executeExitHook(state *State) {
-	f.executeHook(state, state.exitHook)
+// executeActions runs actions of transition, it stops at the first error.
+func (f *FSM) executeActions(ctx context.Context, transition *Transition) error {
+	for _, action := range transition.Actions {
+		f.notifyHook(ctx, transition.To, HookAction)
+		f.log(LogLevelDebug, "start execute action", transitionArgs(transition)...)
+		actionCtx, span := f.startHookSpan(ctx, transition.To, HookAction)
+		start := time.Now()
+		var err error
+		if panicErr := f.protect(transition.To, HookAction, func() {
+			err = action(actionCtx, transition.From.Name, transition.To.Name)
+		}); panicErr != nil {
+			err = panicErr
+		}
+		f.observeHook(transition.To, HookAction, start)
+		endSpan(span, err)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
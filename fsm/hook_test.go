@@ -0,0 +1,155 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"reflect"
+	"testing"
+)
+
+// traced records hooks, actions and state changes of a created -> paid machine.
+func traced(calls *[]string) *fsm.FSM {
+	record := func(call string) func(ctx context.Context, state string) {
+		return func(ctx context.Context, state string) {
+			*calls = append(*calls, call+" "+state)
+		}
+	}
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddTransition("created", "cancelled").
+		AddStateExitHook("created", record("exit")).
+		AddStateEnterHook("paid", record("enter")).
+		AddStateExitHook("paid", record("exit")).
+		AddGlobalExitHook(record("global exit")).
+		AddGlobalEnterHook(record("global enter")).
+		AddTransitionAction("created", "paid", func(ctx context.Context, from, to string) error {
+			*calls = append(*calls, "action "+from+">"+to)
+			return nil
+		})
+	f.OnTransition(func(ctx context.Context, event fsm.TransitionEvent) {
+		*calls = append(*calls, "transited "+event.To)
+	})
+	return f
+}
+
+func TestHookOrder(t *testing.T) {
+	var calls []string
+	f := traced(&calls)
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"exit created",
+		"global exit created",
+		"action created>paid",
+		"global enter paid",
+		"enter paid",
+		"transited paid",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+}
+
+func TestHookOrderSetState(t *testing.T) {
+	var calls []string
+	f := traced(&calls)
+	if err := f.SetState("paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"exit created", "global exit created", "global enter paid", "enter paid", "transited paid"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+}
+
+func TestActionErrorSkipsEnterHooks(t *testing.T) {
+	var calls []string
+	f := traced(&calls).AddTransitionAction("created", "paid", func(ctx context.Context, from, to string) error {
+		return errors.New("payment failed")
+	})
+	if err := f.Transit("paid"); err == nil {
+		t.Fatal("transit passes a failing action")
+	}
+	want := []string{"exit created", "global exit created", "action created>paid"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+	if f.GetCurrentState() != "created" {
+		t.Errorf("state %s after a failing action", f.GetCurrentState())
+	}
+}
+
+func TestFromStatesLimitsEnterHooks(t *testing.T) {
+	var calls []string
+	record := func(ctx context.Context, state string) {
+		calls = append(calls, state)
+	}
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddTransition("created", "cancelled").
+		AddTransition("paid", "cancelled").
+		AddStateEnterHook("cancelled", record, fsm.FromStates("paid")).
+		// exit hooks ignore FromStates
+		AddStateExitHook("created", record, fsm.FromStates("paid"))
+	if err := f.Transit("cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"created"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+}
+
+func TestHookPriority(t *testing.T) {
+	var calls []string
+	record := func(name string) func(ctx context.Context, state string) {
+		return func(ctx context.Context, state string) {
+			calls = append(calls, name)
+		}
+	}
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddStateEnterHook("paid", record("audit"), fsm.WithPriority(100)).
+		AddStateEnterHook("paid", record("first tie")).
+		AddStateEnterHook("paid", record("save"), fsm.WithPriority(-1)).
+		AddStateEnterHook("paid", record("second tie")).
+		AddStateEnterHook("paid", record("third tie"), fsm.WithPriority(0))
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"save", "first tie", "second tie", "third tie", "audit"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+}
+
+func TestRemoveHooks(t *testing.T) {
+	var calls []string
+	record := func(name string) func(ctx context.Context, state string) {
+		return func(ctx context.Context, state string) {
+			calls = append(calls, name)
+		}
+	}
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddStateExitHook("created", record("unlock"), fsm.WithHookName("unlock")).
+		AddStateExitHook("created", record("log")).
+		AddStateEnterHook("paid", record("refund"), fsm.WithHookName("refund")).
+		AddStateEnterHook("paid", record("notify"), fsm.WithHookName("notify")).
+		RemoveStateEnterHook("paid", "refund").
+		RemoveStateExitHook("created", "unlock").
+		// unknown names and states are ignored
+		RemoveStateEnterHook("paid", "missing").
+		RemoveStateExitHook("missing", "log")
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"log", "notify"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,259 @@
+package fsm
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TransitionEvent is published every time an instance changes state,
+// Event is the label of the transition from From to To, if any.
+type TransitionEvent struct {
+	Machine string
+	From    string
+	To      string
+	Event   string
+	At      time.Time
+}
+
+// TransitionFilter selects events, empty fields match anything.
+type TransitionFilter struct {
+	Machines []string
+	// States matches events leaving or entering one of the states
+	States []string
+	From   []string
+	To     []string
+	Events []string
+}
+
+func (filter TransitionFilter) Match(event TransitionEvent) bool {
+	return matchAny(filter.Machines, event.Machine) &&
+		(matchAny(filter.States, event.From) || matchAny(filter.States, event.To)) &&
+		matchAny(filter.From, event.From) &&
+		matchAny(filter.To, event.To) &&
+		matchAny(filter.Events, event.Event)
+}
+
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// TransitionListener is called synchronously after a state change.
+type TransitionListener func(ctx context.Context, event TransitionEvent)
+
+// SlowPolicy decides what happens when a subscriber does not keep up.
+type SlowPolicy int
+
+const (
+	// PolicyDrop discards events once the channel buffer is full, it is the default
+	PolicyDrop SlowPolicy = iota
+	// PolicyBlock makes the transit wait until the subscriber receives or cancels
+	PolicyBlock
+	// PolicyBuffer queues events without limit
+	PolicyBuffer
+)
+
+type subscribeOptions struct {
+	policy SlowPolicy
+	size   int
+}
+
+type SubscribeOption func(o *subscribeOptions)
+
+// WithSlowPolicy sets what happens to events a subscriber is not ready for.
+func WithSlowPolicy(policy SlowPolicy) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.policy = policy
+	}
+}
+
+// WithChannelSize sets the channel buffer, default is 16.
+func WithChannelSize(size int) SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.size = size
+	}
+}
+
+// Bus delivers transition events to listeners and subscribers.
+// Every fsm has its own bus, share one with SetBus to observe several machines together.
+type Bus struct {
+	mu          sync.RWMutex
+	listeners   []TransitionListener
+	subscribers map[*subscriber]bool
+}
+
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[*subscriber]bool)}
+}
+
+// OnTransition calls listener synchronously on every transition, in the order listeners are added.
+func (b *Bus) OnTransition(listener TransitionListener) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.listeners = append(b.listeners, listener)
+}
+
+// Subscribe returns a channel of events matching filter, cancel stops delivery and closes the channel.
+func (b *Bus) Subscribe(filter TransitionFilter, opts ...SubscribeOption) (<-chan TransitionEvent, func()) {
+	o := &subscribeOptions{policy: PolicyDrop, size: 16}
+	for _, opt := range opts {
+		opt(o)
+	}
+	s := &subscriber{
+		filter: filter,
+		policy: o.policy,
+		ch:     make(chan TransitionEvent, o.size),
+		done:   make(chan struct{}),
+		notify: make(chan struct{}, 1),
+	}
+	if s.policy == PolicyBuffer {
+		go s.forward()
+	}
+	b.mu.Lock()
+	b.subscribers[s] = true
+	b.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			b.mu.Lock()
+			delete(b.subscribers, s)
+			b.mu.Unlock()
+			s.close()
+		})
+	}
+	return s.ch, cancel
+}
+
+func (b *Bus) publish(ctx context.Context, event TransitionEvent) {
+	b.mu.RLock()
+	listeners := append([]TransitionListener(nil), b.listeners...)
+	subscribers := make([]*subscriber, 0, len(b.subscribers))
+	for s := range b.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	b.mu.RUnlock()
+
+	for _, listener := range listeners {
+		listener(ctx, event)
+	}
+	for _, s := range subscribers {
+		if s.filter.Match(event) {
+			s.send(event)
+		}
+	}
+}
+
+type subscriber struct {
+	filter TransitionFilter
+	policy SlowPolicy
+	ch     chan TransitionEvent
+	done   chan struct{}
+
+	mu     sync.Mutex
+	closed bool
+	// PolicyBuffer only
+	queue  []TransitionEvent
+	notify chan struct{}
+}
+
+func (s *subscriber) send(event TransitionEvent) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	switch s.policy {
+	case PolicyBlock:
+		select {
+		case s.ch <- event:
+		case <-s.done:
+		}
+	case PolicyBuffer:
+		s.queue = append(s.queue, event)
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	default:
+		select {
+		case s.ch <- event:
+		default:
+		}
+	}
+}
+
+// forward moves queued events to the channel for PolicyBuffer.
+func (s *subscriber) forward() {
+	defer close(s.ch)
+	for {
+		s.mu.Lock()
+		queue := s.queue
+		s.queue = nil
+		s.mu.Unlock()
+		for _, event := range queue {
+			select {
+			case s.ch <- event:
+			case <-s.done:
+				return
+			}
+		}
+		select {
+		case <-s.notify:
+		case <-s.done:
+			return
+		}
+	}
+}
+
+func (s *subscriber) close() {
+	// unblock a waiting send before taking the lock
+	close(s.done)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if s.policy != PolicyBuffer {
+		close(s.ch)
+	}
+}
+
+/***** fsm *****/
+
+// SetBus publishes transitions of f to bus, e.g. one bus shared by all machines of a service.
+func (f *FSM) SetBus(bus *Bus) *FSM {
+	f.bus = bus
+	return f
+}
+
+// OnTransition adds a listener called synchronously after every state change of f,
+// use Bus.OnTransition to listen to every machine of a shared bus.
+func (f *FSM) OnTransition(listener TransitionListener) *FSM {
+	name := f.name
+	f.bus.OnTransition(func(ctx context.Context, event TransitionEvent) {
+		if event.Machine == name {
+			listener(ctx, event)
+		}
+	})
+	return f
+}
+
+// Subscribe returns a channel of transition events of the bus of f, see Bus.Subscribe.
+func (f *FSM) Subscribe(filter TransitionFilter, opts ...SubscribeOption) (<-chan TransitionEvent, func()) {
+	return f.bus.Subscribe(filter, opts...)
+}
+
+func (f *FSM) publishTransition(ctx context.Context, from, to string) {
+	event := TransitionEvent{Machine: f.name, From: from, To: to, At: time.Now()}
+	if t := f.getTransition(from, to); t != nil {
+		event.Event = t.Meta.Label
+	}
+	f.bus.publish(ctx, event)
+}
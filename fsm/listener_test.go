@@ -0,0 +1,179 @@
+package fsm_test
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func toggle(name string) *fsm.FSM {
+	return fsm.NewFSM(context.Background(), name).AddStates("off", "on").
+		SetInitial("off").
+		AddTransition("off", "on").
+		AddTransition("on", "off").
+		SetTransitionMeta("off", "on", fsm.TransitionMeta{Label: "switch_on"})
+}
+
+// flip transits n times between off and on.
+func flip(t *testing.T, f *fsm.FSM, n int) {
+	t.Helper()
+	for i := 0; i < n; i++ {
+		to := "on"
+		if f.GetCurrentState() == "on" {
+			to = "off"
+		}
+		if err := f.Transit(to); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func drain(ch <-chan fsm.TransitionEvent) []string {
+	var got []string
+	for event := range ch {
+		got = append(got, event.From+">"+event.To)
+	}
+	return got
+}
+
+func TestOnTransition(t *testing.T) {
+	bus := fsm.NewBus()
+	a := toggle("a").SetBus(bus)
+	b := toggle("b").SetBus(bus)
+	var mine, all []fsm.TransitionEvent
+	a.OnTransition(func(ctx context.Context, event fsm.TransitionEvent) {
+		mine = append(mine, event)
+	})
+	bus.OnTransition(func(ctx context.Context, event fsm.TransitionEvent) {
+		all = append(all, event)
+	})
+	flip(t, a, 2)
+	flip(t, b, 1)
+
+	if len(mine) != 2 || len(all) != 3 {
+		t.Fatalf("machine listener got %d events, bus listener %d", len(mine), len(all))
+	}
+	if e := mine[0]; e.Machine != "a" || e.From != "off" || e.To != "on" || e.Event != "switch_on" || e.At.IsZero() {
+		t.Errorf("first event %+v", e)
+	}
+	if mine[1].Event != "" {
+		t.Errorf("unlabelled transition has event %q", mine[1].Event)
+	}
+}
+
+func TestSubscribeFilter(t *testing.T) {
+	bus := fsm.NewBus()
+	a := toggle("a").SetBus(bus)
+	b := toggle("b").SetBus(bus)
+	tests := []struct {
+		name   string
+		filter fsm.TransitionFilter
+		want   []string
+	}{
+		{"all", fsm.TransitionFilter{}, []string{"off>on", "on>off", "off>on"}},
+		{"machine", fsm.TransitionFilter{Machines: []string{"b"}}, []string{"off>on"}},
+		{"to", fsm.TransitionFilter{To: []string{"off"}}, []string{"on>off"}},
+		{"event", fsm.TransitionFilter{Events: []string{"switch_on"}}, []string{"off>on", "off>on"}},
+		{"state", fsm.TransitionFilter{Machines: []string{"a"}, States: []string{"on"}}, []string{"off>on", "on>off"}},
+	}
+	channels := make([]<-chan fsm.TransitionEvent, len(tests))
+	cancels := make([]func(), len(tests))
+	for i, tt := range tests {
+		channels[i], cancels[i] = bus.Subscribe(tt.filter)
+	}
+	flip(t, a, 2)
+	flip(t, b, 1)
+	for i, tt := range tests {
+		cancels[i]()
+		if got := drain(channels[i]); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSubscribePolicyDrop(t *testing.T) {
+	f := toggle("a")
+	ch, cancel := f.Subscribe(fsm.TransitionFilter{}, fsm.WithChannelSize(2))
+	flip(t, f, 5)
+	cancel()
+	if got, want := drain(ch), []string{"off>on", "on>off"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want the first %v", got, want)
+	}
+	// cancel is idempotent and later transitions are not sent
+	cancel()
+	flip(t, f, 1)
+}
+
+func TestSubscribePolicyBlock(t *testing.T) {
+	f := toggle("a")
+	ch, cancel := f.Subscribe(fsm.TransitionFilter{}, fsm.WithSlowPolicy(fsm.PolicyBlock), fsm.WithChannelSize(0))
+	defer cancel()
+	done := make(chan error)
+	go func() {
+		var err error
+		for _, to := range []string{"on", "off", "on"} {
+			if err == nil {
+				err = f.Transit(to)
+			}
+		}
+		done <- err
+	}()
+	select {
+	case <-done:
+		t.Fatal("transit does not wait for the subscriber")
+	case <-time.After(20 * time.Millisecond):
+	}
+	var got []string
+	for i := 0; i < 3; i++ {
+		event := <-ch
+		got = append(got, event.From+">"+event.To)
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"off>on", "on>off", "off>on"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestSubscribePolicyBlockCancel(t *testing.T) {
+	f := toggle("a")
+	_, cancel := f.Subscribe(fsm.TransitionFilter{}, fsm.WithSlowPolicy(fsm.PolicyBlock), fsm.WithChannelSize(0))
+	done := make(chan error)
+	go func() {
+		done <- f.Transit("on")
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("cancel does not release a blocked transit")
+	}
+}
+
+func TestSubscribePolicyBuffer(t *testing.T) {
+	f := toggle("a")
+	ch, cancel := f.Subscribe(fsm.TransitionFilter{}, fsm.WithSlowPolicy(fsm.PolicyBuffer), fsm.WithChannelSize(1))
+	defer cancel()
+	flip(t, f, 50)
+	for i := 0; i < 50; i++ {
+		want := "off"
+		if i%2 == 1 {
+			want = "on"
+		}
+		select {
+		case event := <-ch:
+			if event.From != want {
+				t.Fatalf("event %d leaves %s, want %s", i, event.From, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("only %d of 50 events buffered", i)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package fsm
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// Logger takes structured records, args are alternating keys and values
+// such as machine, from, to, event and duration. *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+type LogLevel int
+
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// SetLogger sends records to logger, nil (default) is silent.
+// Build errors such as undefined states still go to log.Fatalf.
+func (f *FSM) SetLogger(logger Logger) *FSM {
+	f.logger = logger
+	return f
+}
+
+// SetLogLevel drops records below level before they reach the logger, default is LogLevelDebug.
+func (f *FSM) SetLogLevel(level LogLevel) *FSM {
+	f.logLevel = level
+	return f
+}
+
+func (f *FSM) log(level LogLevel, msg string, args ...interface{}) {
+	if f.logger == nil || level < f.logLevel {
+		return
+	}
+	args = append([]interface{}{"machine", f.name}, args...)
+	switch level {
+	case LogLevelDebug:
+		f.logger.Debug(msg, args...)
+	case LogLevelInfo:
+		f.logger.Info(msg, args...)
+	case LogLevelWarn:
+		f.logger.Warn(msg, args...)
+	default:
+		f.logger.Error(msg, args...)
+	}
+}
+
+// transitionArgs are the log fields of a transition, event is its label if set.
+func transitionArgs(transition *Transition, args ...interface{}) []interface{} {
+	fields := []interface{}{"from", transition.From.Name, "to", transition.To.Name}
+	if transition.Meta.Label != "" {
+		fields = append(fields, "event", transition.Meta.Label)
+	}
+	return append(fields, args...)
+}
+
+// StdLogger writes records to a standard library logger as "[fsm] LEVEL msg key=value ...",
+// e.g. StdLogger(log.Default()) logs to the standard logger again, in this format rather than the former free text.
+func StdLogger(l *log.Logger) Logger {
+	return stdLogger{l}
+}
+
+type stdLogger struct {
+	l *log.Logger
+}
+
+func (s stdLogger) Debug(msg string, args ...interface{}) { s.print("DEBUG", msg, args) }
+func (s stdLogger) Info(msg string, args ...interface{})  { s.print("INFO", msg, args) }
+func (s stdLogger) Warn(msg string, args ...interface{})  { s.print("WARN", msg, args) }
+func (s stdLogger) Error(msg string, args ...interface{}) { s.print("ERROR", msg, args) }
+
+func (s stdLogger) print(level, msg string, args []interface{}) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[fsm] %s %s", level, msg)
+	for i := 0; i+1 < len(args); i += 2 {
+		fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+	}
+	s.l.Print(b.String())
+}
@@ -0,0 +1,71 @@
+package fsm
+
+import "time"
+
+// transition outcomes reported to Metrics
+const (
+	OutcomeOK       = "ok"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+)
+
+// Metrics receives instrumentation of an instance,
+// see fsmtest.MemoryMetrics for tests and fsmprom for a prometheus registry.
+type Metrics interface {
+	// IncTransition counts a transit attempt by outcome, OutcomeOK, OutcomeRejected or OutcomeError,
+	// only attempts of defined transitions are counted
+	IncTransition(machine, from, to, outcome string)
+	// ObserveGuard records how long a condition took
+	ObserveGuard(machine, from, to string, duration time.Duration)
+	// ObserveHook records how long a hook or action took, kind is HookEnter, HookExit, ...
+	ObserveHook(machine, state, kind string, duration time.Duration)
+	// AddInstances moves the number of live instances in state by delta
+	AddInstances(machine, state string, delta float64)
+}
+
+// SetMetrics starts recording to metrics, the current state counts as a live instance
+// until the state changes or Close is called.
+func (f *FSM) SetMetrics(metrics Metrics) *FSM {
+	f.metrics = metrics
+	if f.currentState != nil {
+		metrics.AddInstances(f.name, f.currentState.Name, 1)
+	}
+	return f
+}
+
+// Close removes the instance from state occupancy metrics, it should be called once an instance is discarded.
+func (f *FSM) Close() {
+	if f.metrics != nil && f.currentState != nil {
+		f.metrics.AddInstances(f.name, f.currentState.Name, -1)
+	}
+	f.metrics = nil
+}
+
+func (f *FSM) countTransition(from, to, outcome string) {
+	if f.metrics != nil {
+		f.metrics.IncTransition(f.name, from, to, outcome)
+	}
+}
+
+func (f *FSM) observeGuard(transition *Transition, start time.Time) {
+	if f.metrics != nil {
+		f.metrics.ObserveGuard(f.name, transition.From.Name, transition.To.Name, time.Since(start))
+	}
+}
+
+func (f *FSM) observeHook(state *State, kind string, start time.Time) {
+	if f.metrics != nil {
+		f.metrics.ObserveHook(f.name, state.Name, kind, time.Since(start))
+	}
+}
+
+// moveInstance updates occupancy when the current state changes.
+func (f *FSM) moveInstance(from, to string) {
+	if f.metrics == nil {
+		return
+	}
+	if from != "" {
+		f.metrics.AddInstances(f.name, from, -1)
+	}
+	f.metrics.AddInstances(f.name, to, 1)
+}
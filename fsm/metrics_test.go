@@ -0,0 +1,53 @@
+package fsm_test
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/fsmtest"
+	"testing"
+)
+
+func TestMetricsOutcomes(t *testing.T) {
+	metrics := fsmtest.NewMemoryMetrics()
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", func(ctx context.Context, state string) (bool, error) {
+			return false, nil
+		}).
+		AddTransition("created", "cancelled").
+		SetMetrics(metrics)
+
+	if err := f.Transit("paid"); err == nil {
+		t.Fatal("condition not met passes")
+	}
+	if err := f.Transit("cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if n := metrics.Transitions("order", "created", "paid", fsm.OutcomeRejected); n != 1 {
+		t.Errorf("rejected created>paid counted %d times", n)
+	}
+	if n := metrics.Transitions("order", "created", "cancelled", fsm.OutcomeOK); n != 1 {
+		t.Errorf("created>cancelled counted %d times", n)
+	}
+	if metrics.Instances("order", "created") != 0 || metrics.Instances("order", "cancelled") != 1 {
+		t.Errorf("instances created %v, cancelled %v", metrics.Instances("order", "created"), metrics.Instances("order", "cancelled"))
+	}
+}
+
+func TestMetricsSkipUndefinedTransitions(t *testing.T) {
+	metrics := fsmtest.NewMemoryMetrics()
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		SetMetrics(metrics)
+	for _, target := range []string{"no-such-state", "created", "user input"} {
+		if err := f.Transit(target); err == nil {
+			t.Fatalf("transit to %s passes", target)
+		}
+		for _, outcome := range []string{fsm.OutcomeOK, fsm.OutcomeRejected, fsm.OutcomeError} {
+			if n := metrics.Transitions("order", "created", target, outcome); n != 0 {
+				t.Errorf("undefined created>%s counted %d times as %s", target, n, outcome)
+			}
+		}
+	}
+}
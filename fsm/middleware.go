@@ -0,0 +1,26 @@
+package fsm
+
+import "context"
+
+// TransitFunc performs a transit along transition, from its condition check to its hooks.
+type TransitFunc func(ctx context.Context, transition *Transition) error
+
+// Middleware wraps every transit, e.g. for auth checks, tracing or a database transaction.
+// It can short-circuit by returning an error without calling next.
+type Middleware func(next TransitFunc) TransitFunc
+
+// Use adds middleware, the first one added is the outermost.
+// Middleware only runs for transitions that exist.
+func (f *FSM) Use(middleware ...Middleware) *FSM {
+	f.middleware = append(f.middleware, middleware...)
+	return f
+}
+
+// chain wraps final with the middleware.
+func (f *FSM) chain(final TransitFunc) TransitFunc {
+	next := final
+	for i := len(f.middleware) - 1; i >= 0; i-- {
+		next = f.middleware[i](next)
+	}
+	return next
+}
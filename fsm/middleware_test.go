@@ -0,0 +1,69 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/fsmtest"
+	"reflect"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var calls []string
+	trace := func(name string) fsm.Middleware {
+		return func(next fsm.TransitFunc) fsm.TransitFunc {
+			return func(ctx context.Context, transition *fsm.Transition) error {
+				calls = append(calls, name+" before")
+				err := next(ctx, transition)
+				calls = append(calls, name+" after")
+				return err
+			}
+		}
+	}
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {
+			calls = append(calls, "enter paid")
+		}).
+		Use(trace("outer"), trace("middle")).
+		Use(trace("inner"))
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"outer before", "middle before", "inner before", "enter paid", "inner after", "middle after", "outer after"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	forbidden := errors.New("forbidden")
+	metrics := fsmtest.NewMemoryMetrics()
+	entered := false
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {
+			entered = true
+		}).
+		SetMetrics(metrics).
+		Use(func(next fsm.TransitFunc) fsm.TransitFunc {
+			return func(ctx context.Context, transition *fsm.Transition) error {
+				return forbidden
+			}
+		})
+	recorder := fsmtest.NewRecorder().Attach(f)
+
+	if err := f.Transit("paid"); err != forbidden {
+		t.Fatalf("Transit returns %v, want the middleware error", err)
+	}
+	if f.GetCurrentState() != "created" || entered {
+		t.Errorf("state %s, hook ran %v after a short circuit", f.GetCurrentState(), entered)
+	}
+	if n := metrics.Transitions("order", "created", "paid", fsm.OutcomeRejected); n != 1 {
+		t.Errorf("rejection counted %d times", n)
+	}
+	fsmtest.AssertRejected(t, recorder, "paid", "forbidden")
+}
@@ -0,0 +1,68 @@
+package fsm
+
+import "context"
+
+// hook kinds reported to observers
+const (
+	HookEnter       = "enter"
+	HookExit        = "exit"
+	HookGlobalEnter = "global enter"
+	HookGlobalExit  = "global exit"
+	// HookAction is reported with the target state for actions of a transition
+	HookAction = "action"
+)
+
+// Observer is notified of everything an instance does while transiting,
+// it is meant for tests and tooling and must not change the fsm.
+type Observer interface {
+	// OnGuard is called after the condition of transition from -> to is evaluated
+	OnGuard(ctx context.Context, from, to string, allowed bool, err error)
+	// OnHook is called before a hook of the given kind is executed
+	OnHook(ctx context.Context, state, kind string)
+	// OnTransit is called once the current state changed
+	OnTransit(ctx context.Context, from, to string)
+	// OnReject is called when a transit fails, from is empty if current state is not set
+	OnReject(ctx context.Context, from, to string, err error)
+}
+
+type forcedKey struct{}
+
+// Forced reports whether observers are notified of a forced move, SetState or the move to the error state,
+// rather than a transit along a transition.
+func Forced(ctx context.Context) bool {
+	forced, _ := ctx.Value(forcedKey{}).(bool)
+	return forced
+}
+
+func withForced(ctx context.Context) context.Context {
+	return context.WithValue(ctx, forcedKey{}, true)
+}
+
+func (f *FSM) AddObserver(observer Observer) *FSM {
+	f.observers = append(f.observers, observer)
+	return f
+}
+
+func (f *FSM) notifyGuard(ctx context.Context, transition *Transition, allowed bool, err error) {
+	for _, o := range f.observers {
+		o.OnGuard(ctx, transition.From.Name, transition.To.Name, allowed, err)
+	}
+}
+
+func (f *FSM) notifyHook(ctx context.Context, state *State, kind string) {
+	for _, o := range f.observers {
+		o.OnHook(ctx, state.Name, kind)
+	}
+}
+
+func (f *FSM) notifyTransit(ctx context.Context, from, to string) {
+	for _, o := range f.observers {
+		o.OnTransit(ctx, from, to)
+	}
+}
+
+func (f *FSM) notifyReject(ctx context.Context, from, to string, err error) {
+	for _, o := range f.observers {
+		o.OnReject(ctx, from, to, err)
+	}
+}
@@ -0,0 +1,83 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+)
+
+// PanicCondition is the Kind of a PanicError raised by a condition,
+// other kinds are the hook kinds HookEnter, HookExit, ...
+const PanicCondition = "condition"
+
+// PanicPolicy decides what happens when a condition, action or hook panics.
+type PanicPolicy int
+
+const (
+	// PanicPropagate lets the panic unwind through Transit, it is the default
+	PanicPropagate PanicPolicy = iota
+	// PanicRecover turns the panic into a *PanicError returned by Transit
+	PanicRecover
+)
+
+// PanicError is a recovered panic, State is the state of the hook, or the target state for conditions and actions.
+type PanicError struct {
+	Machine string
+	State   string
+	Kind    string
+	Value   interface{}
+	Stack   []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("[fsm] %s panic in %s of %s: %v", e.Machine, e.Kind, e.State, e.Value)
+}
+
+// SetPanicPolicy sets how panics of conditions, actions and hooks are handled.
+// When recovered, the current state is left untouched since hooks and actions all run before it changes.
+func (f *FSM) SetPanicPolicy(policy PanicPolicy) *FSM {
+	f.panicPolicy = policy
+	return f
+}
+
+// SetErrorState moves the instance to state after a recovered panic,
+// exit hooks of the current state are skipped and enter hooks of state run as usual.
+func (f *FSM) SetErrorState(state string) *FSM {
+	s := f.getState(state)
+	if s == nil {
+		log.Fatalf("\t[fsm] state not defined %s", state)
+		return nil
+	}
+	f.errorState = s
+	return f
+}
+
+// protect runs fn and recovers a panic into a *PanicError, unless panics propagate.
+func (f *FSM) protect(state *State, kind string, fn func()) (err error) {
+	if f.panicPolicy == PanicPropagate {
+		fn()
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			err = &PanicError{Machine: f.name, State: state.Name, Kind: kind, Value: r, Stack: debug.Stack()}
+		}
+	}()
+	fn()
+	return nil
+}
+
+// enterErrorState moves to the error state if err is a recovered panic.
+func (f *FSM) enterErrorState(ctx context.Context, err error) {
+	var panicErr *PanicError
+	if f.errorState == nil || !errors.As(err, &panicErr) || f.currentState == f.errorState {
+		return
+	}
+	f.log(LogLevelError, "moving to error state", "from", f.GetCurrentState(), "to", f.errorState.Name, "error", err)
+	from := f.GetCurrentState()
+	if err := f.enterState(withForced(ctx), from, f.errorState); err != nil {
+		f.log(LogLevelError, "error state hook failed", "state", f.errorState.Name, "error", err)
+	}
+}
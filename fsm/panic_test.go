@@ -0,0 +1,126 @@
+package fsm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"strings"
+	"testing"
+)
+
+func panicky(ctx context.Context, state string) {
+	panic("boom")
+}
+
+// orderWithPanic builds created -> paid where the step named by kind panics.
+func orderWithPanic(kind string) *fsm.FSM {
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "broken").
+		SetInitial("created")
+	switch kind {
+	case fsm.PanicCondition:
+		f.AddTransitionOn("created", "paid", func(ctx context.Context, state string) (bool, error) {
+			panic("boom")
+		})
+	case fsm.HookAction:
+		f.AddTransition("created", "paid").
+			AddTransitionAction("created", "paid", func(ctx context.Context, from, to string) error {
+				panic("boom")
+			})
+	case fsm.HookExit:
+		f.AddTransition("created", "paid").AddStateExitHook("created", panicky)
+	case fsm.HookEnter:
+		f.AddTransition("created", "paid").AddStateEnterHook("paid", panicky)
+	case fsm.HookGlobalEnter:
+		f.AddTransition("created", "paid").AddGlobalEnterHook(panicky)
+	}
+	return f
+}
+
+func TestPanicRecover(t *testing.T) {
+	tests := []struct {
+		kind  string
+		state string
+	}{
+		{fsm.PanicCondition, "paid"},
+		{fsm.HookAction, "paid"},
+		{fsm.HookExit, "created"},
+		{fsm.HookGlobalEnter, "paid"},
+		{fsm.HookEnter, "paid"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.kind, func(t *testing.T) {
+			f := orderWithPanic(tt.kind).SetPanicPolicy(fsm.PanicRecover)
+			var entered []string
+			f.OnTransition(func(ctx context.Context, event fsm.TransitionEvent) {
+				entered = append(entered, event.To)
+			})
+			err := f.Transit("paid")
+			var panicErr *fsm.PanicError
+			if !errors.As(err, &panicErr) {
+				t.Fatalf("Transit returns %v, want a *fsm.PanicError", err)
+			}
+			if panicErr.Machine != "order" || panicErr.Kind != tt.kind || panicErr.State != tt.state || panicErr.Value != "boom" {
+				t.Errorf("panic error %+v", panicErr)
+			}
+			if !strings.Contains(string(panicErr.Stack), "panic_test.go") {
+				t.Errorf("stack does not point at the panic:\n%s", panicErr.Stack)
+			}
+			if f.GetCurrentState() != "created" || len(entered) != 0 || f.Version() != 1 {
+				t.Errorf("state %s, version %d, entered %v after a panic", f.GetCurrentState(), f.Version(), entered)
+			}
+		})
+	}
+}
+
+func TestPanicErrorState(t *testing.T) {
+	var entered []string
+	f := orderWithPanic(fsm.HookAction).
+		SetPanicPolicy(fsm.PanicRecover).
+		SetErrorState("broken").
+		// exit hooks of the failing state are skipped on the way to the error state
+		AddStateExitHook("created", func(ctx context.Context, state string) {
+			if len(entered) > 0 {
+				t.Error("exit hook of created runs for the error state")
+			}
+		}).
+		AddStateEnterHook("broken", func(ctx context.Context, state string) {
+			entered = append(entered, state)
+		})
+	var panicErr *fsm.PanicError
+	if err := f.Transit("paid"); !errors.As(err, &panicErr) {
+		t.Fatalf("Transit returns %v, want a *fsm.PanicError", err)
+	}
+	if f.GetCurrentState() != "broken" || len(entered) != 1 {
+		t.Errorf("state %s, broken entered %d times", f.GetCurrentState(), len(entered))
+	}
+}
+
+func TestErrorStateIgnoresErrors(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "broken").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", func(ctx context.Context, state string) (bool, error) {
+			return false, errors.New("payment service down")
+		}).
+		SetPanicPolicy(fsm.PanicRecover).
+		SetErrorState("broken")
+	if err := f.Transit("paid"); err == nil {
+		t.Fatal("failing condition passes")
+	}
+	if f.GetCurrentState() != "created" {
+		t.Errorf("state %s after a plain error", f.GetCurrentState())
+	}
+}
+
+func TestPanicPropagateByDefault(t *testing.T) {
+	f := orderWithPanic(fsm.HookEnter).SetErrorState("broken")
+	defer func() {
+		if r := recover(); r != "boom" {
+			t.Errorf("recovered %v, want boom", r)
+		}
+		if f.GetCurrentState() != "created" {
+			t.Errorf("state %s after a panic", f.GetCurrentState())
+		}
+	}()
+	f.Transit("paid")
+	t.Error("panic did not propagate")
+}
@@ -0,0 +1,127 @@
+package fsm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+)
+
+// SetTransitionCost sets the weight ShortestPath gives to a transition, default is 1.
+func (f *FSM) SetTransitionCost(from, to string, cost float64) *FSM {
+	t := f.getTransition(from, to)
+	if t == nil {
+		log.Fatalf("\t[fsm] transition not defined from %s to %s", from, to)
+		return nil
+	}
+	if cost < 0 {
+		log.Fatalf("\t[fsm] transition cost can not be negative %s %v", t.Key, cost)
+		return nil
+	}
+	t.Cost = cost
+	return f
+}
+
+// ShortestPath returns the cheapest states path from one state to another, both included.
+// Among paths of equal cost the one through states and transitions added first wins.
+// only check transition link, do not check condition
+func (f *FSM) ShortestPath(from, to string) ([]string, error) {
+	if !f.hasState(from) {
+		return nil, errors.New(fmt.Sprintf("[fsm] state not defined %s", from))
+	}
+	if !f.hasState(to) {
+		return nil, errors.New(fmt.Sprintf("[fsm] state not defined %s", to))
+	}
+
+	// dijkstra, the fsm is small enough to scan for the closest state.
+	// States are scanned in the order they are added so ties are broken the same way every time.
+	dist := map[string]float64{from: 0}
+	prev := make(map[string]string)
+	visited := make(map[string]bool)
+	for {
+		current, best := "", math.Inf(1)
+		for _, s := range f.states {
+			if d, ok := dist[s.Name]; ok && !visited[s.Name] && d < best {
+				current, best = s.Name, d
+			}
+		}
+		if current == "" {
+			return nil, errors.New(fmt.Sprintf("[fsm] no path from %s to %s", from, to))
+		}
+		if current == to {
+			break
+		}
+		visited[current] = true
+		for _, t := range f.getAvailableTransitions(current) {
+			next := t.To.Name
+			if d, ok := dist[next]; !ok || best+t.Cost < d {
+				dist[next] = best + t.Cost
+				prev[next] = current
+			}
+		}
+	}
+
+	path := []string{to}
+	for state := to; state != from; {
+		state = prev[state]
+		path = append([]string{state}, path...)
+	}
+	return path, nil
+}
+
+// AllPaths returns every path from one state to another that has at most maxDepth transitions
+// and visits no state twice, in depth first order.
+// only check transition link, do not check condition
+func (f *FSM) AllPaths(from, to string, maxDepth int) [][]string {
+	var paths [][]string
+	if !f.hasState(from) || !f.hasState(to) {
+		return paths
+	}
+	visiting := map[string]bool{from: true}
+	var walk func(path []string)
+	walk = func(path []string) {
+		current := path[len(path)-1]
+		if current == to {
+			paths = append(paths, append([]string(nil), path...))
+			return
+		}
+		if len(path)-1 >= maxDepth {
+			return
+		}
+		for _, t := range f.getAvailableTransitions(current) {
+			next := t.To.Name
+			if visiting[next] {
+				continue
+			}
+			visiting[next] = true
+			walk(append(path, next))
+			visiting[next] = false
+		}
+	}
+	walk([]string{from})
+	return paths
+}
+
+// TransitVia walks the shortest path from the current state to target,
+// each step runs conditions and hooks as Transit does.
+// It stops at the first rejected step and stays in the state reached.
+func (f *FSM) TransitVia(ctx context.Context, target string) error {
+	if f.currentState == nil {
+		err := errors.New(fmt.Sprintf("\t[fsm] current state not set, transit to %s", target))
+		f.log(LogLevelWarn, "transit via rejected", "to", target, "error", err)
+		return err
+	}
+	path, err := f.ShortestPath(f.currentState.Name, target)
+	if err != nil {
+		f.log(LogLevelWarn, "transit via rejected", "from", f.currentState.Name, "to", target, "error", err)
+		return err
+	}
+	f.log(LogLevelInfo, "transit via", "from", f.currentState.Name, "to", target, "path", path)
+	for _, state := range path[1:] {
+		if err := f.transit(ctx, state); err != nil {
+			return fmt.Errorf("[fsm] transit via %v stopped at %s: %w", path, f.currentState.Name, err)
+		}
+	}
+	return nil
+}
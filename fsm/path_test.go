@@ -0,0 +1,70 @@
+package fsm_test
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"reflect"
+	"testing"
+)
+
+func diamond() *fsm.FSM {
+	return fsm.NewFSM(context.Background(), "diamond").AddStates("a", "b", "c", "d").
+		SetInitial("a").
+		AddTransition("a", "b").
+		AddTransition("a", "c").
+		AddTransition("b", "d").
+		AddTransition("c", "d")
+}
+
+func TestShortestPathTieIsDeterministic(t *testing.T) {
+	f := diamond()
+	for i := 0; i < 200; i++ {
+		path, err := f.ShortestPath("a", "d")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := []string{"a", "b", "d"}; !reflect.DeepEqual(path, want) {
+			t.Fatalf("run %d: ShortestPath = %v, want %v", i, path, want)
+		}
+	}
+}
+
+func TestShortestPathCost(t *testing.T) {
+	f := diamond().SetTransitionCost("a", "b", 5)
+	path, err := f.ShortestPath("a", "d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"a", "c", "d"}; !reflect.DeepEqual(path, want) {
+		t.Errorf("ShortestPath = %v, want %v", path, want)
+	}
+	if _, err := f.ShortestPath("d", "a"); err == nil {
+		t.Error("found a path from d to a")
+	}
+}
+
+func TestAllPaths(t *testing.T) {
+	paths := diamond().AllPaths("a", "d", 2)
+	want := [][]string{{"a", "b", "d"}, {"a", "c", "d"}}
+	if !reflect.DeepEqual(paths, want) {
+		t.Errorf("AllPaths = %v, want %v", paths, want)
+	}
+	if paths := diamond().AllPaths("a", "d", 1); len(paths) != 0 {
+		t.Errorf("AllPaths with depth 1 = %v", paths)
+	}
+}
+
+func TestTransitViaStopsAtRejectedStep(t *testing.T) {
+	var visited []string
+	f := fsm.NewFSM(context.Background(), "t").AddStates("a", "b", "c").
+		SetInitial("a").
+		AddTransition("a", "b").
+		AddTransitionOn("b", "c", func(ctx context.Context, state string) (bool, error) { return false, nil }).
+		AddGlobalEnterHook(func(ctx context.Context, state string) { visited = append(visited, state) })
+	if err := f.TransitVia(context.Background(), "c"); err == nil {
+		t.Fatal("TransitVia passed a denied condition")
+	}
+	if f.GetCurrentState() != "b" || !reflect.DeepEqual(visited, []string{"b"}) {
+		t.Errorf("stopped in %s after %v, want b", f.GetCurrentState(), visited)
+	}
+}
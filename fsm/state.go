@@ -1,17 +1,106 @@
 package fsm
 
-import "context"
+import (
+	"context"
+	"sort"
+)
+
+// TagTerminal marks a state as terminal, it is drawn as a double circle and Analyze treats it as final.
+// Unlike AddFinalStates it does not forbid outgoing transitions nor close Done.
+const TagTerminal = "terminal"
 
 type State struct {
-	Name      string
-	enterHook func(ctx context.Context, state string)
-	exitHook  func(ctx context.Context, state string)
+	Name       string
+	Meta       StateMeta
+	final      bool
+	enterHooks []*Hook
+	exitHooks  []*Hook
+}
+
+// StateMeta documents a state and controls how it is drawn.
+// Empty fields fall back to defaults.
+type StateMeta struct {
+	Label       string
+	Description string
+	Color       string
+	Shape       string
+	Tags        []string
 }
 
+// SetEnterHook and SetExitHook replace all hooks of the state with a single one.
 func (s *State) SetEnterHook(hook func(ctx context.Context, state string)) {
-	s.enterHook = hook
+	s.enterHooks = nil
+	if hook != nil {
+		s.AddEnterHook(NewHook(hook))
+	}
 }
 
 func (s *State) SetExitHook(hook func(ctx context.Context, state string)) {
-	s.exitHook = hook
+	s.exitHooks = nil
+	if hook != nil {
+		s.AddExitHook(NewHook(hook))
+	}
+}
+
+// AddEnterHook and AddExitHook keep hooks ordered by priority, then by the order they are added.
+func (s *State) AddEnterHook(hook *Hook) {
+	s.enterHooks = insertHook(s.enterHooks, hook)
+}
+
+func (s *State) AddExitHook(hook *Hook) {
+	s.exitHooks = insertHook(s.exitHooks, hook)
+}
+
+// RemoveEnterHook and RemoveExitHook remove hooks by name, they report whether any was removed.
+func (s *State) RemoveEnterHook(name string) bool {
+	var removed bool
+	s.enterHooks, removed = removeHook(s.enterHooks, name)
+	return removed
+}
+
+func (s *State) RemoveExitHook(name string) bool {
+	var removed bool
+	s.exitHooks, removed = removeHook(s.exitHooks, name)
+	return removed
+}
+
+// EnterHooks and ExitHooks return hooks in the order they run.
+func (s *State) EnterHooks() []*Hook {
+	return append([]*Hook(nil), s.enterHooks...)
+}
+
+func (s *State) ExitHooks() []*Hook {
+	return append([]*Hook(nil), s.exitHooks...)
+}
+
+func insertHook(hooks []*Hook, hook *Hook) []*Hook {
+	hooks = append(hooks, hook)
+	sort.SliceStable(hooks, func(i, j int) bool {
+		return hooks[i].Priority < hooks[j].Priority
+	})
+	return hooks
+}
+
+func removeHook(hooks []*Hook, name string) ([]*Hook, bool) {
+	kept := make([]*Hook, 0, len(hooks))
+	for _, h := range hooks {
+		if h.Name != name {
+			kept = append(kept, h)
+		}
+	}
+	return kept, len(kept) < len(hooks)
+}
+
+// IsFinal reports whether the state is declared by AddFinalStates.
+func (s *State) IsFinal() bool {
+	return s.final
+}
+
+func (s *State) HasTag(tag string) bool {
+	for _, t := range s.Meta.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,63 @@
+package fsm
+
+import "context"
+
+// Span is the part of a tracing span the fsm needs.
+type Span interface {
+	RecordError(err error)
+	End()
+}
+
+// Tracer starts a span per transit with child spans for its condition, actions and hooks,
+// see fsmotel for OpenTelemetry. The context returned is passed on to conditions and hooks.
+type Tracer interface {
+	// StartTransit is called for every transit, event is the transition label, if any
+	StartTransit(ctx context.Context, machine, from, to, event string) (context.Context, Span)
+	StartGuard(ctx context.Context, machine, from, to string) (context.Context, Span)
+	// StartHook is called for hooks and actions, kind is HookEnter, HookExit, ...
+	StartHook(ctx context.Context, machine, state, kind string) (context.Context, Span)
+}
+
+func (f *FSM) SetTracer(tracer Tracer) *FSM {
+	f.tracer = tracer
+	return f
+}
+
+type noopSpan struct{}
+
+func (noopSpan) RecordError(err error) {}
+
+func (noopSpan) End() {}
+
+func (f *FSM) startTransitSpan(ctx context.Context, to string) (context.Context, Span) {
+	if f.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	from := f.GetCurrentState()
+	var event string
+	if t := f.getTransition(from, to); t != nil {
+		event = t.Meta.Label
+	}
+	return f.tracer.StartTransit(ctx, f.name, from, to, event)
+}
+
+func (f *FSM) startGuardSpan(ctx context.Context, transition *Transition) (context.Context, Span) {
+	if f.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return f.tracer.StartGuard(ctx, f.name, transition.From.Name, transition.To.Name)
+}
+
+func (f *FSM) startHookSpan(ctx context.Context, state *State, kind string) (context.Context, Span) {
+	if f.tracer == nil {
+		return ctx, noopSpan{}
+	}
+	return f.tracer.StartHook(ctx, f.name, state.Name, kind)
+}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+	span.End()
+}
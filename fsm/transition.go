@@ -10,6 +10,22 @@ type Transition struct {
 	To        *State
 	Key       string
 	Condition func(ctx context.Context, currentState string) (bool, error)
+	// ConditionName is the name of the guard added with AddTransitionGuard, if any
+	ConditionName string
+	// Actions run in order once the condition passed, between exit hooks of the source state and enter hooks of the target state
+	Actions []func(ctx context.Context, from, to string) error
+	Meta    TransitionMeta
+	// Cost is the weight of the transition for ShortestPath
+	Cost float64
+}
+
+// TransitionMeta documents a transition and controls how it is drawn.
+// Empty fields fall back to defaults.
+type TransitionMeta struct {
+	Label       string
+	Description string
+	Color       string
+	Tags        []string
 }
 
 func NewTransition(from, to *State, condition func(ctx context.Context, currentState string) (bool, error)) *Transition {
@@ -18,6 +34,7 @@ func NewTransition(from, to *State, condition func(ctx context.Context, currentS
 		To:        to,
 		Key:       GenTransitionKey(from.Name, to.Name),
 		Condition: condition,
+		Cost:      1,
 	}
 }
 
@@ -0,0 +1,12 @@
+package fsmcheck
+
+/*
+fsmcheck model checks fsm definitions.
+Properties are CTL formulas built from go combinators and checked against the definition graph,
+conditions are treated as nondeterministic choices so every defined transition may be taken.
+A failed check returns a counterexample path from the initial state.
+It also generates test cases walking the definition under a coverage criterion,
+and emits them as a table driven go test.
+Simulate runs seeded random walks on live instances checking invariants after every step,
+a failing walk is shrunk to a minimal sequence, FuzzWalk plugs the same walks into go test -fuzz.
+*/
@@ -0,0 +1,23 @@
+//go:build go1.18
+// +build go1.18
+
+package fsmcheck
+
+import "testing"
+
+// FuzzOrderWalk explores the order machine with go test -fuzz=FuzzOrderWalk ./fsmcheck
+func FuzzOrderWalk(f *testing.F) {
+	f.Add([]byte{0, 0})
+	f.Add([]byte{1, 0, 0, 0, 0, 0, 0, 0})
+	f.Add([]byte{1, 1, 1, 0, 0, 1, 0, 0, 0, 0})
+	config := SimConfig{
+		New:        orderFSM,
+		Guards:     RandomGuards(0.5),
+		Invariants: []Invariant{finalStaysFinal, neverDeliveredAfterCancel},
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		if failure := FuzzWalk(config, data); failure != nil {
+			t.Fatal(failure)
+		}
+	})
+}
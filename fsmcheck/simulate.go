@@ -0,0 +1,257 @@
+package fsmcheck
+
+import (
+	"context"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"math/rand"
+	"strings"
+)
+
+// GuardStrategy decides conditions during a simulation, evaluate runs the real condition.
+type GuardStrategy func(rnd *rand.Rand, transition *fsm.Transition, evaluate func() (bool, error)) (bool, error)
+
+// RealGuards evaluates the conditions, it is the default.
+func RealGuards(rnd *rand.Rand, transition *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+	return evaluate()
+}
+
+// AllowGuards lets every condition pass.
+func AllowGuards(rnd *rand.Rand, transition *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+	return true, nil
+}
+
+// RandomGuards lets a condition pass with probability p, without evaluating it.
+func RandomGuards(p float64) GuardStrategy {
+	return func(rnd *rand.Rand, transition *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+		return rnd.Float64() < p, nil
+	}
+}
+
+// Invariant must hold after every step of a walk,
+// history holds the states the instance has been in, starting with the initial one.
+type Invariant struct {
+	Name  string
+	Check func(f *fsm.FSM, history []string) error
+}
+
+type SimConfig struct {
+	// New returns a fresh instance in its initial state, it is called once per walk.
+	New        func() *fsm.FSM
+	Seed       int64
+	Walks      int // default 100
+	MaxSteps   int // default 50
+	Guards     GuardStrategy
+	Invariants []Invariant
+}
+
+// WalkStep is one Transit call of a walk with the condition outcome chosen for it,
+// Allow and Err are only used if the transition has a condition.
+type WalkStep struct {
+	Target string
+	Allow  bool
+	Err    error
+}
+
+func (s WalkStep) String() string {
+	switch {
+	case s.Err != nil:
+		return fmt.Sprintf("%s(err)", s.Target)
+	case !s.Allow:
+		return fmt.Sprintf("%s(denied)", s.Target)
+	}
+	return s.Target
+}
+
+// SimFailure is a walk breaking an invariant, Walk is shrunk to a minimal reproducing sequence.
+type SimFailure struct {
+	Invariant string
+	Err       error
+	Seed      int64
+	Walk      []WalkStep
+	Original  []WalkStep
+}
+
+func (f *SimFailure) Error() string {
+	return fmt.Sprintf("[fsm] invariant %s broken after %s: %s", f.Invariant, joinSteps(f.Walk), f.Err)
+}
+
+type SimResult struct {
+	Walks   int
+	Steps   int
+	Failure *SimFailure
+}
+
+// Simulate runs seeded random walks, each step transits to a random target available from the current state.
+// It stops at the first broken invariant and shrinks the walk, the same seed gives the same walks.
+func Simulate(config SimConfig) *SimResult {
+	if config.Walks == 0 {
+		config.Walks = 100
+	}
+	if config.MaxSteps == 0 {
+		config.MaxSteps = 50
+	}
+	if config.Guards == nil {
+		config.Guards = RealGuards
+	}
+	result := &SimResult{}
+	rnd := rand.New(rand.NewSource(config.Seed))
+	for i := 0; i < config.Walks; i++ {
+		walk, failure := randomWalk(config, rnd)
+		result.Walks++
+		result.Steps += len(walk)
+		if failure != nil {
+			failure.Walk = walk
+			if shrunk := shrink(config, walk, failure.Invariant); shrunk != nil {
+				failure = shrunk
+			}
+			failure.Seed = config.Seed
+			failure.Original = walk
+			result.Failure = failure
+			return result
+		}
+	}
+	return result
+}
+
+// FuzzWalk replays a walk decoded from fuzz input, two bytes per step:
+// the first picks the target among available ones, the second seeds the guard strategy.
+// Call it from a native fuzz target:
+//
+//	f.Fuzz(func(t *testing.T, data []byte) {
+//		if failure := fsmcheck.FuzzWalk(config, data); failure != nil {
+//			t.Fatal(failure)
+//		}
+//	})
+func FuzzWalk(config SimConfig, data []byte) *SimFailure {
+	if config.Guards == nil {
+		config.Guards = RealGuards
+	}
+	instance := config.New()
+	history := []string{instance.GetCurrentState()}
+	var current WalkStep
+	var walk []WalkStep
+	var rnd *rand.Rand
+	instance.SetConditionOverride(func(ctx context.Context, t *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+		current.Allow, current.Err = config.Guards(rnd, t, evaluate)
+		return current.Allow, current.Err
+	})
+	for i := 0; i+1 < len(data); i += 2 {
+		targets := instance.GetAvailableStateNames()
+		if len(targets) == 0 {
+			break
+		}
+		current = WalkStep{Target: targets[int(data[i])%len(targets)], Allow: true}
+		rnd = rand.New(rand.NewSource(int64(data[i+1])))
+		if instance.Transit(current.Target) == nil {
+			history = append(history, current.Target)
+		}
+		walk = append(walk, current)
+		if failure := checkInvariants(config, instance, history); failure != nil {
+			failure.Walk = walk
+			if shrunk := shrink(config, walk, failure.Invariant); shrunk != nil {
+				failure = shrunk
+			}
+			failure.Original = walk
+			return failure
+		}
+	}
+	return nil
+}
+
+// Replay runs steps on a fresh instance with the recorded condition outcomes,
+// and returns the first broken invariant.
+func Replay(config SimConfig, steps []WalkStep) *SimFailure {
+	instance := config.New()
+	history := []string{instance.GetCurrentState()}
+	var current WalkStep
+	instance.SetConditionOverride(func(ctx context.Context, t *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+		return current.Allow, current.Err
+	})
+	for i, step := range steps {
+		current = step
+		if instance.Transit(step.Target) == nil {
+			history = append(history, step.Target)
+		}
+		if failure := checkInvariants(config, instance, history); failure != nil {
+			failure.Walk = steps[:i+1]
+			return failure
+		}
+	}
+	return nil
+}
+
+func randomWalk(config SimConfig, rnd *rand.Rand) ([]WalkStep, *SimFailure) {
+	instance := config.New()
+	history := []string{instance.GetCurrentState()}
+	var current WalkStep
+	instance.SetConditionOverride(func(ctx context.Context, t *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+		current.Allow, current.Err = config.Guards(rnd, t, evaluate)
+		return current.Allow, current.Err
+	})
+	var walk []WalkStep
+	for i := 0; i < config.MaxSteps; i++ {
+		targets := instance.GetAvailableStateNames()
+		if len(targets) == 0 {
+			break
+		}
+		current = WalkStep{Target: targets[rnd.Intn(len(targets))], Allow: true}
+		if instance.Transit(current.Target) == nil {
+			history = append(history, current.Target)
+		}
+		walk = append(walk, current)
+		if failure := checkInvariants(config, instance, history); failure != nil {
+			return walk, failure
+		}
+	}
+	return walk, nil
+}
+
+func checkInvariants(config SimConfig, instance *fsm.FSM, history []string) *SimFailure {
+	for _, invariant := range config.Invariants {
+		if err := invariant.Check(instance, history); err != nil {
+			return &SimFailure{Invariant: invariant.Name, Err: err}
+		}
+	}
+	return nil
+}
+
+// shrink removes chunks of steps, halving the chunk size, as long as the same invariant still breaks.
+// It returns nil if the walk does not reproduce, e.g. hooks depending on outside state.
+func shrink(config SimConfig, walk []WalkStep, invariant string) *SimFailure {
+	fails := func(steps []WalkStep) *SimFailure {
+		if failure := Replay(config, steps); failure != nil && failure.Invariant == invariant {
+			return failure
+		}
+		return nil
+	}
+	best := fails(walk)
+	if best == nil {
+		return nil
+	}
+	for size := len(best.Walk) / 2; size >= 1; {
+		shrunk := false
+		for start := 0; start+size <= len(best.Walk); start++ {
+			candidate := append(append([]WalkStep(nil), best.Walk[:start]...), best.Walk[start+size:]...)
+			if failure := fails(candidate); failure != nil {
+				best = failure
+				shrunk = true
+				break
+			}
+		}
+		if !shrunk {
+			size /= 2
+		} else if size > len(best.Walk) {
+			size = len(best.Walk)
+		}
+	}
+	return best
+}
+
+func joinSteps(steps []WalkStep) string {
+	parts := make([]string, 0, len(steps))
+	for _, s := range steps {
+		parts = append(parts, s.String())
+	}
+	return strings.Join(parts, " -> ")
+}
@@ -0,0 +1,128 @@
+package fsmcheck
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"reflect"
+	"testing"
+)
+
+// buggyOrderFSM lets a cancelled order be delivered.
+func buggyOrderFSM() *fsm.FSM {
+	return fsm.NewFSM(context.Background(), "order").
+		AddStates("created", "paid", "cancelled", "delivering", "finished").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddTransition("created", "cancelled").
+		AddTransition("paid", "created").
+		AddTransition("paid", "cancelled").
+		AddTransition("paid", "delivering").
+		AddTransitionOn("cancelled", "delivering", isPhysical).
+		AddTransition("delivering", "finished")
+}
+
+var neverDeliveredAfterCancel = Invariant{
+	Name: "never delivered after cancel",
+	Check: func(f *fsm.FSM, history []string) error {
+		cancelled := false
+		for _, state := range history {
+			if state == "cancelled" {
+				cancelled = true
+			}
+			if state == "delivering" && cancelled {
+				return errors.New("cancelled order is delivering")
+			}
+		}
+		return nil
+	},
+}
+
+// finalStaysFinal holds for orderFSM, final states are never left.
+var finalStaysFinal = Invariant{
+	Name: "final stays final",
+	Check: func(f *fsm.FSM, history []string) error {
+		for i, state := range history[:len(history)-1] {
+			if (state == "finished" || state == "cancelled") && history[i+1] != state {
+				return errors.New("left final state " + state)
+			}
+		}
+		return nil
+	},
+}
+
+func TestSimulateFindsAndShrinks(t *testing.T) {
+	config := SimConfig{
+		New:        buggyOrderFSM,
+		Seed:       7,
+		Walks:      200,
+		MaxSteps:   30,
+		Guards:     RandomGuards(0.5),
+		Invariants: []Invariant{neverDeliveredAfterCancel},
+	}
+	result := Simulate(config)
+	failure := result.Failure
+	if failure == nil {
+		t.Fatalf("no failure in %d walks", result.Walks)
+	}
+	if failure.Invariant != neverDeliveredAfterCancel.Name || failure.Seed != 7 {
+		t.Errorf("failure %v with seed %d", failure, failure.Seed)
+	}
+	if len(failure.Walk) > len(failure.Original) {
+		t.Errorf("shrunk walk %v is longer than %v", failure.Walk, failure.Original)
+	}
+	// the smallest walk cancels, then delivers with the condition passing
+	want := []WalkStep{{Target: "cancelled", Allow: true}, {Target: "delivering", Allow: true}}
+	if !reflect.DeepEqual(failure.Walk, want) {
+		t.Errorf("shrunk walk %v, want %v", failure.Walk, want)
+	}
+
+	replayed := Replay(config, failure.Walk)
+	if replayed == nil || replayed.Invariant != failure.Invariant {
+		t.Errorf("replay of %v gives %v", failure.Walk, replayed)
+	}
+
+	again := Simulate(config)
+	if again.Failure == nil || !reflect.DeepEqual(again.Failure.Original, failure.Original) {
+		t.Errorf("same seed gives another walk: %v", again.Failure)
+	}
+}
+
+func TestSimulateHolds(t *testing.T) {
+	result := Simulate(SimConfig{
+		New:        orderFSM,
+		Seed:       1,
+		Walks:      50,
+		Guards:     AllowGuards,
+		Invariants: []Invariant{finalStaysFinal, neverDeliveredAfterCancel},
+	})
+	if result.Failure != nil {
+		t.Fatal(result.Failure)
+	}
+	if result.Walks != 50 || result.Steps == 0 {
+		t.Errorf("ran %d walks of %d steps", result.Walks, result.Steps)
+	}
+}
+
+func TestReplayDeniedStep(t *testing.T) {
+	config := SimConfig{New: buggyOrderFSM, Invariants: []Invariant{neverDeliveredAfterCancel}}
+	denied := []WalkStep{{Target: "cancelled", Allow: true}, {Target: "delivering", Allow: false}}
+	if failure := Replay(config, denied); failure != nil {
+		t.Errorf("denied delivery breaks the invariant: %v", failure)
+	}
+}
+
+func TestFuzzWalk(t *testing.T) {
+	config := SimConfig{New: buggyOrderFSM, Guards: AllowGuards, Invariants: []Invariant{neverDeliveredAfterCancel}}
+	// available from created: paid, cancelled; from cancelled: delivering
+	failure := FuzzWalk(config, []byte{1, 0, 0, 0})
+	if failure == nil {
+		t.Fatal("cancel then deliver passes")
+	}
+	if failure.Invariant != neverDeliveredAfterCancel.Name {
+		t.Errorf("failure %v", failure)
+	}
+	if failure := FuzzWalk(config, []byte{0, 0, 1, 0}); failure != nil {
+		t.Errorf("paid then cancelled fails: %v", failure)
+	}
+}
@@ -0,0 +1,441 @@
+package fsmcheck
+
+import (
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"strings"
+)
+
+// Formula is a CTL formula over the states of a fsm definition.
+// Conditions are treated as nondeterministic, any transition may be taken.
+// A state without outgoing transition loops on itself, so every path is infinite.
+type Formula interface {
+	String() string
+	// sat returns the states the formula holds in
+	sat(m *model) stateSet
+}
+
+// Result of Check, Counterexample is a path from the start state showing why
+// the formula fails. A repeated last state means the path loops there forever.
+type Result struct {
+	Formula        string
+	Holds          bool
+	Counterexample []string
+}
+
+func (r *Result) String() string {
+	if r.Holds {
+		return fmt.Sprintf("%s holds", r.Formula)
+	}
+	return fmt.Sprintf("%s fails: %s", r.Formula, strings.Join(r.Counterexample, " -> "))
+}
+
+// Check model checks the formula from the initial state of f,
+// or its first state if no initial state is set.
+func Check(f *fsm.FSM, formula Formula) *Result {
+	start := f.GetInitialState()
+	if start == "" && len(f.States()) > 0 {
+		start = f.States()[0].Name
+	}
+	return CheckFrom(f, start, formula)
+}
+
+// CheckFrom model checks the formula from the given state.
+func CheckFrom(f *fsm.FSM, state string, formula Formula) *Result {
+	m := newModel(f)
+	result := &Result{Formula: formula.String()}
+	s, ok := m.index[state]
+	if !ok {
+		result.Counterexample = []string{state}
+		return result
+	}
+	if formula.sat(m)[s] {
+		result.Holds = true
+		return result
+	}
+	result.Counterexample = m.names(m.counterexample(formula, s))
+	return result
+}
+
+/***** properties *****/
+
+// NeverAfter: once trigger is entered, forbidden is never entered afterwards.
+// e.g. NeverAfter("cancelled", "delivered")
+func NeverAfter(trigger, forbidden string) Formula {
+	return named(fmt.Sprintf("never %s after %s", forbidden, trigger),
+		AG(Implies(In(trigger), AX(AG(Not(In(forbidden)))))))
+}
+
+// EventuallyReaches: every path through from eventually enters one of targets.
+// e.g. EventuallyReaches("created", "finished", "cancelled")
+func EventuallyReaches(from string, targets ...string) Formula {
+	return named(fmt.Sprintf("%s eventually reaches %s", from, strings.Join(targets, " or ")),
+		AG(Implies(In(from), AF(In(targets...)))))
+}
+
+// AlwaysPrecededBy: state is never entered before predecessor has been entered.
+// e.g. AlwaysPrecededBy("paid", "created")
+func AlwaysPrecededBy(state, predecessor string) Formula {
+	return named(fmt.Sprintf("%s always preceded by %s", state, predecessor),
+		Not(EU(Not(In(predecessor)), And(In(state), Not(In(predecessor))))))
+}
+
+/***** formulas *****/
+
+// In holds in any of the given states.
+func In(states ...string) Formula { return &atom{states: states} }
+
+func True() Formula { return &constant{value: true} }
+
+func Not(f Formula) Formula { return &not{f: f} }
+
+func And(fs ...Formula) Formula { return &and{fs: fs} }
+
+func Or(fs ...Formula) Formula { return &or{fs: fs} }
+
+func Implies(a, b Formula) Formula {
+	return named(fmt.Sprintf("(%s -> %s)", a, b), Or(Not(a), b))
+}
+
+// EX holds if some next state satisfies f.
+func EX(f Formula) Formula { return &ex{f: f} }
+
+// AX holds if every next state satisfies f.
+func AX(f Formula) Formula { return named("AX "+f.String(), Not(EX(Not(f)))) }
+
+// EU holds if some path satisfies a until b holds.
+func EU(a, b Formula) Formula { return &eu{a: a, b: b} }
+
+// EF holds if some path eventually reaches f.
+func EF(f Formula) Formula { return named("EF "+f.String(), EU(True(), f)) }
+
+// EG holds if some path satisfies f forever.
+func EG(f Formula) Formula { return &eg{f: f} }
+
+// AG holds if every path satisfies f forever.
+func AG(f Formula) Formula { return named("AG "+f.String(), Not(EF(Not(f)))) }
+
+// AF holds if every path eventually reaches f.
+func AF(f Formula) Formula { return named("AF "+f.String(), Not(EG(Not(f)))) }
+
+// AU holds if every path satisfies a until b holds.
+func AU(a, b Formula) Formula {
+	return named(fmt.Sprintf("A[%s U %s]", a, b),
+		Not(Or(EU(Not(b), And(Not(a), Not(b))), EG(Not(b)))))
+}
+
+type stateSet []bool
+
+type model struct {
+	states []string
+	index  map[string]int
+	succ   [][]int
+}
+
+func newModel(f *fsm.FSM) *model {
+	m := &model{index: make(map[string]int)}
+	for i, s := range f.States() {
+		m.states = append(m.states, s.Name)
+		m.index[s.Name] = i
+	}
+	m.succ = make([][]int, len(m.states))
+	for _, t := range f.Transitions() {
+		from, to := m.index[t.From.Name], m.index[t.To.Name]
+		m.succ[from] = append(m.succ[from], to)
+	}
+	for s := range m.succ {
+		if len(m.succ[s]) == 0 {
+			m.succ[s] = []int{s}
+		}
+	}
+	return m
+}
+
+func (m *model) names(path []int) []string {
+	names := make([]string, 0, len(path))
+	for _, s := range path {
+		names = append(names, m.states[s])
+	}
+	return names
+}
+
+func (m *model) newSet(value bool) stateSet {
+	set := make(stateSet, len(m.states))
+	for i := range set {
+		set[i] = value
+	}
+	return set
+}
+
+// pre returns states having some successor in set.
+func (m *model) pre(set stateSet) stateSet {
+	result := m.newSet(false)
+	for s, succ := range m.succ {
+		for _, t := range succ {
+			if set[t] {
+				result[s] = true
+				break
+			}
+		}
+	}
+	return result
+}
+
+type atom struct{ states []string }
+
+func (f *atom) String() string { return strings.Join(f.states, "|") }
+
+func (f *atom) sat(m *model) stateSet {
+	set := m.newSet(false)
+	for _, name := range f.states {
+		if i, ok := m.index[name]; ok {
+			set[i] = true
+		}
+	}
+	return set
+}
+
+type constant struct{ value bool }
+
+func (f *constant) String() string { return fmt.Sprint(f.value) }
+
+func (f *constant) sat(m *model) stateSet { return m.newSet(f.value) }
+
+type not struct{ f Formula }
+
+func (f *not) String() string { return "!" + f.f.String() }
+
+func (f *not) sat(m *model) stateSet {
+	set := f.f.sat(m)
+	result := m.newSet(false)
+	for i := range set {
+		result[i] = !set[i]
+	}
+	return result
+}
+
+type and struct{ fs []Formula }
+
+func (f *and) String() string { return join(f.fs, " & ") }
+
+func (f *and) sat(m *model) stateSet {
+	result := m.newSet(true)
+	for _, sub := range f.fs {
+		set := sub.sat(m)
+		for i := range result {
+			result[i] = result[i] && set[i]
+		}
+	}
+	return result
+}
+
+type or struct{ fs []Formula }
+
+func (f *or) String() string { return join(f.fs, " | ") }
+
+func (f *or) sat(m *model) stateSet {
+	result := m.newSet(false)
+	for _, sub := range f.fs {
+		set := sub.sat(m)
+		for i := range result {
+			result[i] = result[i] || set[i]
+		}
+	}
+	return result
+}
+
+type ex struct{ f Formula }
+
+func (f *ex) String() string { return "EX " + f.f.String() }
+
+func (f *ex) sat(m *model) stateSet { return m.pre(f.f.sat(m)) }
+
+type eu struct{ a, b Formula }
+
+func (f *eu) String() string { return fmt.Sprintf("E[%s U %s]", f.a, f.b) }
+
+// least fixpoint of Z = b | (a & EX Z)
+func (f *eu) sat(m *model) stateSet {
+	a, b := f.a.sat(m), f.b.sat(m)
+	z := append(stateSet(nil), b...)
+	for changed := true; changed; {
+		changed = false
+		pre := m.pre(z)
+		for s := range z {
+			if !z[s] && a[s] && pre[s] {
+				z[s] = true
+				changed = true
+			}
+		}
+	}
+	return z
+}
+
+type eg struct{ f Formula }
+
+func (f *eg) String() string { return "EG " + f.f.String() }
+
+// greatest fixpoint of Z = f & EX Z
+func (f *eg) sat(m *model) stateSet {
+	z := f.f.sat(m)
+	for changed := true; changed; {
+		changed = false
+		pre := m.pre(z)
+		for s := range z {
+			if z[s] && !pre[s] {
+				z[s] = false
+				changed = true
+			}
+		}
+	}
+	return z
+}
+
+// namedFormula gives a readable name to a composed formula.
+type namedFormula struct {
+	name string
+	Formula
+}
+
+func named(name string, f Formula) Formula { return &namedFormula{name: name, Formula: f} }
+
+func (f *namedFormula) String() string { return f.name }
+
+func join(fs []Formula, sep string) string {
+	parts := make([]string, 0, len(fs))
+	for _, f := range fs {
+		parts = append(parts, "("+f.String()+")")
+	}
+	return strings.Join(parts, sep)
+}
+
+/***** counterexamples *****/
+
+// counterexample explains why formula fails in state s.
+func (m *model) counterexample(formula Formula, s int) []int {
+	switch f := formula.(type) {
+	case *namedFormula:
+		return m.counterexample(f.Formula, s)
+	case *not:
+		return m.witness(f.f, s)
+	case *and:
+		for _, sub := range f.fs {
+			if !sub.sat(m)[s] {
+				return m.counterexample(sub, s)
+			}
+		}
+	case *or:
+		// every disjunct fails, the longest explanation is the interesting one
+		var longest []int
+		for _, sub := range f.fs {
+			if path := m.counterexample(sub, s); len(path) > len(longest) {
+				longest = path
+			}
+		}
+		if longest != nil {
+			return longest
+		}
+	case *ex:
+		next := m.succ[s][0]
+		return append([]int{s}, m.counterexample(f.f, next)...)
+	}
+	return []int{s}
+}
+
+// witness shows why formula holds in state s.
+func (m *model) witness(formula Formula, s int) []int {
+	switch f := formula.(type) {
+	case *namedFormula:
+		return m.witness(f.Formula, s)
+	case *not:
+		return m.counterexample(f.f, s)
+	case *and:
+		// follow the first conjunct that is not a plain state check
+		for _, sub := range f.fs {
+			if path := m.witness(sub, s); len(path) > 1 {
+				return path
+			}
+		}
+	case *or:
+		for _, sub := range f.fs {
+			if sub.sat(m)[s] {
+				return m.witness(sub, s)
+			}
+		}
+	case *ex:
+		set := f.f.sat(m)
+		for _, t := range m.succ[s] {
+			if set[t] {
+				return append([]int{s}, m.witness(f.f, t)...)
+			}
+		}
+	case *eu:
+		a, b := f.a.sat(m), f.b.sat(m)
+		path := m.search(s, func(t int) bool { return b[t] }, func(t int) bool { return a[t] })
+		if path != nil {
+			return concat(path, m.witness(f.b, path[len(path)-1]))
+		}
+	case *eg:
+		return m.lasso(s, f.sat(m))
+	}
+	return []int{s}
+}
+
+// search finds the shortest path from s to a goal state, passing only through allowed states.
+func (m *model) search(s int, goal, allowed func(int) bool) []int {
+	prev := map[int]int{s: -1}
+	queue := []int{s}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if goal(current) {
+			path := []int{current}
+			for p := prev[current]; p != -1; p = prev[p] {
+				path = append([]int{p}, path...)
+			}
+			return path
+		}
+		if !allowed(current) {
+			continue
+		}
+		for _, t := range m.succ[current] {
+			if _, seen := prev[t]; !seen {
+				prev[t] = current
+				queue = append(queue, t)
+			}
+		}
+	}
+	return nil
+}
+
+// lasso follows states in set from s until one repeats.
+func (m *model) lasso(s int, set stateSet) []int {
+	path := []int{s}
+	seen := map[int]bool{s: true}
+	for current := s; ; {
+		next := -1
+		for _, t := range m.succ[current] {
+			if set[t] {
+				next = t
+				break
+			}
+		}
+		if next == -1 {
+			return path
+		}
+		path = append(path, next)
+		if seen[next] {
+			return path
+		}
+		seen[next] = true
+		current = next
+	}
+}
+
+// concat joins two paths where the second starts at the end of the first.
+func concat(a, b []int) []int {
+	if len(b) == 0 {
+		return a
+	}
+	return append(append([]int(nil), a...), b[1:]...)
+}
@@ -0,0 +1,117 @@
+package fsmcheck
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"testing"
+)
+
+func isPhysical(ctx context.Context, state string) (bool, error) {
+	return true, nil
+}
+
+func isVirtual(ctx context.Context, state string) (bool, error) {
+	return false, nil
+}
+
+// orderFSM is the order machine of the README.
+func orderFSM() *fsm.FSM {
+	return fsm.NewFSM(context.Background(), "order").
+		AddStates("created", "cancelled", "paid", "checkout", "delivering", "delivered", "finished").
+		SetInitial("created").
+		AddTransition("created", "cancelled").
+		AddTransition("created", "paid").
+		AddTransition("paid", "checkout").
+		AddTransition("checkout", "delivering").
+		AddTransition("delivering", "delivered").
+		AddTransition("delivered", "finished").
+		AddTransitionOn("checkout", "finished", isVirtual).
+		AddTransitionOn("paid", "cancelled", isPhysical).
+		AddFinalStates("finished", "cancelled")
+}
+
+// checkPath fails unless path starts in start and only follows transitions of f,
+// a repeated last state stands for a state looping forever.
+func checkPath(t *testing.T, f *fsm.FSM, start string, path []string) {
+	t.Helper()
+	if len(path) == 0 || path[0] != start {
+		t.Fatalf("path %v does not start in %s", path, start)
+	}
+	for i := 1; i < len(path); i++ {
+		if path[i] == path[i-1] && i == len(path)-1 {
+			continue
+		}
+		if !hasTransition(f, path[i-1], path[i]) {
+			t.Errorf("path %v takes missing transition %s->%s", path, path[i-1], path[i])
+		}
+	}
+}
+
+func TestCheckOrderProperties(t *testing.T) {
+	f := orderFSM()
+	tests := []struct {
+		formula Formula
+		holds   bool
+		// last state of the counterexample
+		last string
+	}{
+		{NeverAfter("cancelled", "delivered"), true, ""},
+		{EventuallyReaches("created", "finished", "cancelled"), true, ""},
+		{AlwaysPrecededBy("checkout", "paid"), true, ""},
+		{AlwaysPrecededBy("finished", "delivered"), false, "finished"},
+		{EF(In("cancelled")), true, ""},
+		{AG(Not(In("delivering"))), false, "delivering"},
+		{AF(In("finished")), false, "cancelled"},
+		{EG(Not(In("finished"))), true, ""},
+		{AU(Not(In("finished")), In("cancelled", "finished")), true, ""},
+		{AX(In("paid")), false, "cancelled"},
+		{Implies(In("created"), EX(In("paid"))), true, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.formula.String(), func(t *testing.T) {
+			result := Check(f, tt.formula)
+			if result.Holds != tt.holds {
+				t.Fatalf("%s, want holds=%v", result, tt.holds)
+			}
+			if tt.holds {
+				if result.Counterexample != nil {
+					t.Errorf("holding formula has counterexample %v", result.Counterexample)
+				}
+				return
+			}
+			checkPath(t, f, "created", result.Counterexample)
+			if last := result.Counterexample[len(result.Counterexample)-1]; last != tt.last {
+				t.Errorf("counterexample %v ends in %s, want %s", result.Counterexample, last, tt.last)
+			}
+		})
+	}
+}
+
+func TestCheckFrom(t *testing.T) {
+	f := orderFSM()
+	if result := CheckFrom(f, "delivering", AF(In("finished"))); !result.Holds {
+		t.Errorf("%s from delivering", result)
+	}
+	if result := CheckFrom(f, "missing", True()); result.Holds {
+		t.Errorf("%s from an undefined state", result)
+	}
+}
+
+func TestCheckCounterexampleLoops(t *testing.T) {
+	// a and b loop forever without reaching done
+	f := fsm.NewFSM(context.Background(), "loop").AddStates("a", "b", "done").
+		SetInitial("a").
+		AddTransition("a", "b").
+		AddTransition("b", "a").
+		AddTransition("b", "done").
+		AddFinalStates("done")
+	result := Check(f, AF(In("done")))
+	if result.Holds {
+		t.Fatalf("%s, the a b loop never finishes", result)
+	}
+	for _, state := range result.Counterexample {
+		if state == "done" {
+			t.Errorf("counterexample %v reaches done", result.Counterexample)
+		}
+	}
+}
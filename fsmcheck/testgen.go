@@ -0,0 +1,279 @@
+package fsmcheck
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"go/format"
+	"io"
+	"strings"
+	"text/template"
+)
+
+// Step is one Transit call and its expected outcome.
+type Step struct {
+	Target string
+	// WantErr is set for an illegal target, the state must not change
+	WantErr bool
+	// Want is the current state expected after the call
+	Want string
+}
+
+// TestCase starts from a fresh instance in Start state and runs Steps in order.
+type TestCase struct {
+	Name  string
+	Start string
+	Steps []Step
+}
+
+// CoverageCriterion decides what generated test cases must cover.
+type CoverageCriterion interface {
+	Name() string
+	// Requirements returns state sequences a test case has to walk through consecutively.
+	Requirements(f *fsm.FSM) [][]string
+}
+
+// AllStates requires every state to be entered.
+type AllStates struct{}
+
+func (AllStates) Name() string { return "all states" }
+
+func (AllStates) Requirements(f *fsm.FSM) [][]string {
+	var requirements [][]string
+	for _, s := range f.States() {
+		requirements = append(requirements, []string{s.Name})
+	}
+	return requirements
+}
+
+// AllTransitions requires every transition to be taken.
+type AllTransitions struct{}
+
+func (AllTransitions) Name() string { return "all transitions" }
+
+func (AllTransitions) Requirements(f *fsm.FSM) [][]string {
+	var requirements [][]string
+	for _, t := range f.Transitions() {
+		requirements = append(requirements, []string{t.From.Name, t.To.Name})
+	}
+	return requirements
+}
+
+// AllTransitionPairs requires every two adjacent transitions to be taken one after another.
+type AllTransitionPairs struct{}
+
+func (AllTransitionPairs) Name() string { return "all transition pairs" }
+
+func (AllTransitionPairs) Requirements(f *fsm.FSM) [][]string {
+	var requirements [][]string
+	for _, first := range f.Transitions() {
+		for _, second := range f.Transitions() {
+			if first.To.Name == second.From.Name {
+				requirements = append(requirements, []string{first.From.Name, first.To.Name, second.To.Name})
+			}
+		}
+	}
+	return requirements
+}
+
+// GenerateTests walks the definition from its initial state and returns test cases
+// covering every requirement of criterion reachable from there.
+// Conditions are not evaluated, the instance under test has to let the walked transitions pass.
+func GenerateTests(f *fsm.FSM, criterion CoverageCriterion) []*TestCase {
+	start := startState(f)
+	if start == "" {
+		return nil
+	}
+	var cases []*TestCase
+	// a fresh instance is already in start state
+	covered := [][]string{{start}}
+	for _, requirement := range criterion.Requirements(f) {
+		if containsAny(covered, requirement) {
+			continue
+		}
+		path := searchPath(f, start, requirement[0])
+		if path == nil {
+			continue
+		}
+		path = append(path, requirement[1:]...)
+		covered = append(covered, path)
+		cases = append(cases, &TestCase{
+			Name:  fmt.Sprintf("%s %s", criterion.Name(), strings.Join(requirement, "->")),
+			Start: start,
+			Steps: walk(path),
+		})
+	}
+	return cases
+}
+
+// GenerateNegativeTests returns a test case for every illegal (state, target) pair
+// with a reachable state, the last step expects Transit to fail.
+func GenerateNegativeTests(f *fsm.FSM) []*TestCase {
+	start := startState(f)
+	if start == "" {
+		return nil
+	}
+	var cases []*TestCase
+	for _, from := range f.States() {
+		path := searchPath(f, start, from.Name)
+		if path == nil {
+			continue
+		}
+		for _, to := range f.States() {
+			if hasTransition(f, from.Name, to.Name) {
+				continue
+			}
+			steps := append(walk(path), Step{Target: to.Name, WantErr: true, Want: from.Name})
+			cases = append(cases, &TestCase{
+				Name:  fmt.Sprintf("reject %s->%s", from.Name, to.Name),
+				Start: start,
+				Steps: steps,
+			})
+		}
+	}
+	return cases
+}
+
+// GoTestConfig configures the emitted go test file.
+type GoTestConfig struct {
+	Package  string
+	TestName string
+	// Constructor is a go expression returning a fresh instance in its initial state,
+	// e.g. "newOrderFSM()". It needs Transit(string) error and GetCurrentState() string.
+	Constructor string
+}
+
+var goTestTemplate = template.Must(template.New("test").Parse(`// Code generated by fsmcheck. DO NOT EDIT.
+
+package {{.Config.Package}}
+
+import "testing"
+
+func {{.Config.TestName}}(t *testing.T) {
+	cases := []struct {
+		name  string
+		steps []struct {
+			target  string
+			wantErr bool
+			want    string
+		}
+	}{
+{{- range .Cases}}
+		{ {{printf "%q" .Name}}, []struct {
+			target  string
+			wantErr bool
+			want    string
+		}{
+{{- range .Steps}}
+			{ {{printf "%q" .Target}}, {{.WantErr}}, {{printf "%q" .Want}} },
+{{- end}}
+		}},
+{{- end}}
+	}
+	for _, c := range cases {
+		c := c
+		t.Run(c.name, func(t *testing.T) {
+			f := {{.Config.Constructor}}
+			for i, step := range c.steps {
+				err := f.Transit(step.target)
+				if (err != nil) != step.wantErr {
+					t.Fatalf("step %d transit to %s: err %v, want err %v", i, step.target, err, step.wantErr)
+				}
+				if got := f.GetCurrentState(); got != step.want {
+					t.Fatalf("step %d transit to %s: state %s, want %s", i, step.target, got, step.want)
+				}
+			}
+		})
+	}
+}
+`))
+
+// WriteGoTests emits a gofmt-ed table driven go test running the cases.
+func WriteGoTests(w io.Writer, config GoTestConfig, cases []*TestCase) error {
+	var buf bytes.Buffer
+	err := goTestTemplate.Execute(&buf, struct {
+		Config GoTestConfig
+		Cases  []*TestCase
+	}{config, cases})
+	if err != nil {
+		return err
+	}
+	source, err := format.Source(buf.Bytes())
+	if err != nil {
+		return errors.New(fmt.Sprintf("[fsm] format generated tests: %s", err))
+	}
+	_, err = w.Write(source)
+	return err
+}
+
+func startState(f *fsm.FSM) string {
+	if start := f.GetInitialState(); start != "" {
+		return start
+	}
+	if states := f.States(); len(states) > 0 {
+		return states[0].Name
+	}
+	return ""
+}
+
+func hasTransition(f *fsm.FSM, from, to string) bool {
+	for _, t := range f.Transitions() {
+		if t.From.Name == from && t.To.Name == to {
+			return true
+		}
+	}
+	return false
+}
+
+// searchPath is a breadth first search in definition order so generated cases are stable.
+func searchPath(f *fsm.FSM, from, to string) []string {
+	prev := map[string]string{from: ""}
+	queue := []string{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == to {
+			path := []string{current}
+			for p := prev[current]; p != ""; p = prev[p] {
+				path = append([]string{p}, path...)
+			}
+			return path
+		}
+		for _, t := range f.Transitions() {
+			if _, seen := prev[t.To.Name]; t.From.Name == current && !seen {
+				prev[t.To.Name] = current
+				queue = append(queue, t.To.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// walk turns a states path into steps, the first state is where the instance starts.
+func walk(path []string) []Step {
+	steps := make([]Step, 0, len(path))
+	for _, state := range path[1:] {
+		steps = append(steps, Step{Target: state, Want: state})
+	}
+	return steps
+}
+
+// containsAny reports whether requirement appears consecutively in any of paths.
+func containsAny(paths [][]string, requirement []string) bool {
+	for _, path := range paths {
+		for i := 0; i+len(requirement) <= len(path); i++ {
+			match := true
+			for j, state := range requirement {
+				if path[i+j] != state {
+					match = false
+					break
+				}
+			}
+			if match {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package fsmcheck
+
+import (
+	"bytes"
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"go/parser"
+	"go/token"
+	"strings"
+	"testing"
+)
+
+func allowAll(ctx context.Context, transition *fsm.Transition, evaluate func() (bool, error)) (bool, error) {
+	return true, nil
+}
+
+// runCase runs c on a fresh order instance whose conditions all pass,
+// it returns the transitions taken.
+func runCase(t *testing.T, c *TestCase) []string {
+	t.Helper()
+	f := orderFSM().SetConditionOverride(allowAll)
+	if f.GetCurrentState() != c.Start {
+		t.Fatalf("%s: starts in %s, instance is in %s", c.Name, c.Start, f.GetCurrentState())
+	}
+	var taken []string
+	for i, step := range c.Steps {
+		from := f.GetCurrentState()
+		err := f.Transit(step.Target)
+		if (err != nil) != step.WantErr {
+			t.Fatalf("%s: step %d to %s: err %v, want err %v", c.Name, i, step.Target, err, step.WantErr)
+		}
+		if got := f.GetCurrentState(); got != step.Want {
+			t.Fatalf("%s: step %d to %s: state %s, want %s", c.Name, i, step.Target, got, step.Want)
+		}
+		if err == nil {
+			taken = append(taken, fsm.GenTransitionKey(from, step.Target))
+		}
+	}
+	return taken
+}
+
+func TestGenerateTestsCoverAllTransitions(t *testing.T) {
+	f := orderFSM()
+	covered := make(map[string]bool)
+	for _, c := range GenerateTests(f, AllTransitions{}) {
+		for _, key := range runCase(t, c) {
+			covered[key] = true
+		}
+	}
+	for _, transition := range f.Transitions() {
+		if !covered[transition.Key] {
+			t.Errorf("transition %s is not covered", transition.Key)
+		}
+	}
+}
+
+func TestGenerateTestsCoverAllStates(t *testing.T) {
+	f := orderFSM()
+	entered := map[string]bool{"created": true}
+	for _, c := range GenerateTests(f, AllStates{}) {
+		runCase(t, c)
+		for _, step := range c.Steps {
+			entered[step.Want] = true
+		}
+	}
+	for _, s := range f.States() {
+		if !entered[s.Name] {
+			t.Errorf("state %s is not entered", s.Name)
+		}
+	}
+}
+
+func TestGenerateNegativeTests(t *testing.T) {
+	cases := GenerateNegativeTests(orderFSM())
+	if len(cases) == 0 {
+		t.Fatal("no negative case")
+	}
+	for _, c := range cases {
+		last := c.Steps[len(c.Steps)-1]
+		if !last.WantErr {
+			t.Errorf("%s: last step expects success", c.Name)
+		}
+		runCase(t, c)
+	}
+}
+
+func TestWriteGoTests(t *testing.T) {
+	var buf bytes.Buffer
+	config := GoTestConfig{Package: "order", TestName: "TestOrderGenerated", Constructor: "newOrderFSM()"}
+	if err := WriteGoTests(&buf, config, GenerateTests(orderFSM(), AllTransitions{})); err != nil {
+		t.Fatal(err)
+	}
+	file, err := parser.ParseFile(token.NewFileSet(), "generated_test.go", buf.Bytes(), 0)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, buf.String())
+	}
+	if file.Name.Name != "order" || !strings.Contains(buf.String(), "func TestOrderGenerated(t *testing.T)") {
+		t.Errorf("unexpected generated source:\n%s", buf.String())
+	}
+}
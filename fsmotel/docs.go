@@ -0,0 +1,6 @@
+package fsmotel
+
+/*
+fsmotel traces fsm transits with OpenTelemetry.
+It lives in its own package so fsm does not depend on the OpenTelemetry api.
+*/
@@ -0,0 +1,85 @@
+package fsmotel
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/FingerLiu/go-fsm/fsmotel"
+
+// span attributes
+const (
+	AttrMachine  = attribute.Key("fsm.machine")
+	AttrFrom     = attribute.Key("fsm.from")
+	AttrTo       = attribute.Key("fsm.to")
+	AttrEvent    = attribute.Key("fsm.event")
+	AttrState    = attribute.Key("fsm.state")
+	AttrHookKind = attribute.Key("fsm.hook.kind")
+	AttrReason   = attribute.Key("fsm.reason")
+)
+
+// Tracer is a fsm.Tracer creating spans fsm.transit, with children fsm.guard and fsm.hook.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+// New traces with provider, nil uses the global provider.
+func New(provider trace.TracerProvider) *Tracer {
+	if provider == nil {
+		provider = otel.GetTracerProvider()
+	}
+	return &Tracer{tracer: provider.Tracer(instrumentationName)}
+}
+
+func (t *Tracer) StartTransit(ctx context.Context, machine, from, to, event string) (context.Context, fsm.Span) {
+	ctx, span := t.tracer.Start(ctx, "fsm.transit", trace.WithAttributes(
+		AttrMachine.String(machine),
+		AttrFrom.String(from),
+		AttrTo.String(to),
+		AttrEvent.String(event),
+	))
+	return ctx, spanAdapter{span}
+}
+
+func (t *Tracer) StartGuard(ctx context.Context, machine, from, to string) (context.Context, fsm.Span) {
+	ctx, span := t.tracer.Start(ctx, "fsm.guard", trace.WithAttributes(
+		AttrMachine.String(machine),
+		AttrFrom.String(from),
+		AttrTo.String(to),
+	))
+	return ctx, spanAdapter{span}
+}
+
+func (t *Tracer) StartHook(ctx context.Context, machine, state, kind string) (context.Context, fsm.Span) {
+	ctx, span := t.tracer.Start(ctx, "fsm.hook", trace.WithAttributes(
+		AttrMachine.String(machine),
+		AttrState.String(state),
+		AttrHookKind.String(kind),
+	))
+	return ctx, spanAdapter{span}
+}
+
+// spanAdapter marks the span as failed along with recording the error,
+// a *fsm.Denial is an expected answer and only adds an fsm.denied event.
+type spanAdapter struct {
+	span trace.Span
+}
+
+func (s spanAdapter) RecordError(err error) {
+	var denial *fsm.Denial
+	if errors.As(err, &denial) {
+		s.span.AddEvent("fsm.denied", trace.WithAttributes(AttrReason.String(denial.Code)))
+		return
+	}
+	s.span.RecordError(err)
+	s.span.SetStatus(codes.Error, err.Error())
+}
+
+func (s spanAdapter) End() {
+	s.span.End()
+}
@@ -0,0 +1,155 @@
+package fsmotel
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func newExporter() (*tracetest.InMemoryExporter, *Tracer) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	return exporter, New(provider)
+}
+
+func orderFSM(tracer *Tracer, condition fsm.Condition) *fsm.FSM {
+	return fsm.NewFSM(context.Background(), "order").AddStates("created", "paid").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", condition).
+		SetTransitionMeta("created", "paid", fsm.TransitionMeta{Label: "pay"}).
+		AddTransitionAction("created", "paid", func(ctx context.Context, from, to string) error {
+			return nil
+		}).
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {}).
+		SetTracer(tracer)
+}
+
+func attrs(span tracetest.SpanStub) map[attribute.Key]string {
+	m := make(map[attribute.Key]string)
+	for _, kv := range span.Attributes {
+		m[kv.Key] = kv.Value.AsString()
+	}
+	return m
+}
+
+func TestSpanTree(t *testing.T) {
+	exporter, tracer := newExporter()
+	f := orderFSM(tracer, func(ctx context.Context, state string) (bool, error) {
+		return true, nil
+	})
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+
+	spans := exporter.GetSpans()
+	var transit tracetest.SpanStub
+	children := make(map[string][]tracetest.SpanStub)
+	for _, span := range spans {
+		if span.Name == "fsm.transit" {
+			transit = span
+		} else {
+			children[span.Name] = append(children[span.Name], span)
+		}
+	}
+	if !transit.SpanContext.IsValid() {
+		t.Fatalf("no fsm.transit span in %d spans", len(spans))
+	}
+	want := map[attribute.Key]string{AttrMachine: "order", AttrFrom: "created", AttrTo: "paid", AttrEvent: "pay"}
+	for key, value := range want {
+		if got := attrs(transit)[key]; got != value {
+			t.Errorf("transit %s = %q, want %q", key, got, value)
+		}
+	}
+	if transit.Status.Code == codes.Error {
+		t.Errorf("transit status %v", transit.Status)
+	}
+
+	if len(children["fsm.guard"]) != 1 || len(children["fsm.hook"]) != 2 {
+		t.Fatalf("%d guard spans and %d hook spans", len(children["fsm.guard"]), len(children["fsm.hook"]))
+	}
+	kinds := make(map[string]bool)
+	for _, span := range append(children["fsm.guard"], children["fsm.hook"]...) {
+		if span.Parent.SpanID() != transit.SpanContext.SpanID() || span.Parent.TraceID() != transit.SpanContext.TraceID() {
+			t.Errorf("%s span is not a child of fsm.transit", span.Name)
+		}
+		if span.Name == "fsm.hook" {
+			kinds[attrs(span)[AttrHookKind]] = true
+			if got := attrs(span)[AttrState]; got != "paid" {
+				t.Errorf("hook state %q", got)
+			}
+		}
+	}
+	if !kinds[fsm.HookAction] || !kinds[fsm.HookEnter] {
+		t.Errorf("hook kinds %v", kinds)
+	}
+}
+
+func TestSpanErrorStatus(t *testing.T) {
+	exporter, tracer := newExporter()
+	f := orderFSM(tracer, func(ctx context.Context, state string) (bool, error) {
+		return false, errors.New("payment service down")
+	})
+	if err := f.Transit("paid"); err == nil {
+		t.Fatal("failing condition passes")
+	}
+	for _, span := range exporter.GetSpans() {
+		if span.Name == "fsm.hook" {
+			t.Errorf("hook ran after a failing condition")
+			continue
+		}
+		if span.Status.Code != codes.Error || span.Status.Description != "payment service down" {
+			t.Errorf("%s status %v", span.Name, span.Status)
+		}
+		if len(span.Events) != 1 || span.Events[0].Name != "exception" {
+			t.Errorf("%s events %v", span.Name, span.Events)
+		}
+	}
+}
+
+func TestSpanDenialIsNotAnError(t *testing.T) {
+	exporter, tracer := newExporter()
+	f := orderFSM(tracer, func(ctx context.Context, state string) (bool, error) {
+		return false, fsm.Deny("balance", "not enough balance", nil)
+	})
+	if err := f.Transit("paid"); err == nil {
+		t.Fatal("denied condition passes")
+	}
+	spans := exporter.GetSpans()
+	if len(spans) != 2 {
+		t.Fatalf("%d spans, want transit and guard", len(spans))
+	}
+	for _, span := range spans {
+		if span.Status.Code == codes.Error {
+			t.Errorf("%s status %v for a denial", span.Name, span.Status)
+		}
+		if len(span.Events) != 1 || span.Events[0].Name != "fsm.denied" {
+			t.Errorf("%s events %v", span.Name, span.Events)
+			continue
+		}
+		if got := span.Events[0].Attributes; len(got) != 1 || got[0] != AttrReason.String("balance") {
+			t.Errorf("%s denial attributes %v", span.Name, got)
+		}
+	}
+}
+
+func TestSpanFalseConditionIsNotAnError(t *testing.T) {
+	exporter, tracer := newExporter()
+	f := orderFSM(tracer, func(ctx context.Context, state string) (bool, error) {
+		return false, nil
+	})
+	err := f.Transit("paid")
+	var denial *fsm.Denial
+	if !errors.As(err, &denial) || denial.Code != fsm.ReasonConditionNotMet {
+		t.Fatalf("Transit returns %v, want a condition not met denial", err)
+	}
+	for _, span := range exporter.GetSpans() {
+		if span.Status.Code == codes.Error {
+			t.Errorf("%s status %v for a false condition", span.Name, span.Status)
+		}
+	}
+}
@@ -0,0 +1,6 @@
+package fsmprom
+
+/*
+fsmprom exposes fsm metrics through a prometheus registry.
+It lives in its own package so fsm does not depend on the prometheus client.
+*/
@@ -0,0 +1,61 @@
+package fsmprom
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"time"
+)
+
+// Metrics is a fsm.Metrics backed by prometheus collectors:
+// fsm_transitions_total, fsm_guard_duration_seconds, fsm_hook_duration_seconds and fsm_instances.
+type Metrics struct {
+	transitions *prometheus.CounterVec
+	guards      *prometheus.HistogramVec
+	hooks       *prometheus.HistogramVec
+	instances   *prometheus.GaugeVec
+}
+
+// New registers the collectors with registerer, e.g. prometheus.DefaultRegisterer.
+func New(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		transitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "fsm_transitions_total",
+			Help: "Transit attempts by machine, source, target and outcome.",
+		}, []string{"machine", "from", "to", "outcome"}),
+		guards: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "fsm_guard_duration_seconds",
+			Help:    "Condition evaluation latency by transition.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"machine", "from", "to"}),
+		hooks: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "fsm_hook_duration_seconds",
+			Help:    "Hook and action latency by state and kind.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"machine", "state", "kind"}),
+		instances: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "fsm_instances",
+			Help: "Live instances by machine and current state.",
+		}, []string{"machine", "state"}),
+	}
+	for _, c := range []prometheus.Collector{m.transitions, m.guards, m.hooks, m.instances} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) IncTransition(machine, from, to, outcome string) {
+	m.transitions.WithLabelValues(machine, from, to, outcome).Inc()
+}
+
+func (m *Metrics) ObserveGuard(machine, from, to string, duration time.Duration) {
+	m.guards.WithLabelValues(machine, from, to).Observe(duration.Seconds())
+}
+
+func (m *Metrics) ObserveHook(machine, state, kind string, duration time.Duration) {
+	m.hooks.WithLabelValues(machine, state, kind).Observe(duration.Seconds())
+}
+
+func (m *Metrics) AddInstances(machine, state string, delta float64) {
+	m.instances.WithLabelValues(machine, state).Add(delta)
+}
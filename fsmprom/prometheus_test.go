@@ -0,0 +1,76 @@
+package fsmprom
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := New(registry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allowed := false
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", func(ctx context.Context, state string) (bool, error) {
+			return allowed, nil
+		}).
+		AddTransition("created", "cancelled").
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {}).
+		SetMetrics(metrics)
+
+	if err := f.Transit("paid"); err == nil {
+		t.Fatal("condition not met passes")
+	}
+	allowed = true
+	if err := f.Transit("paid"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		collector prometheus.Collector
+		want      float64
+	}{
+		{metrics.transitions.WithLabelValues("order", "created", "paid", fsm.OutcomeOK), 1},
+		{metrics.transitions.WithLabelValues("order", "created", "paid", fsm.OutcomeRejected), 1},
+		{metrics.instances.WithLabelValues("order", "created"), 0},
+		{metrics.instances.WithLabelValues("order", "paid"), 1},
+	}
+	for i, tt := range tests {
+		if got := testutil.ToFloat64(tt.collector); got != tt.want {
+			t.Errorf("metric %d = %v, want %v", i, got, tt.want)
+		}
+	}
+
+	expected := `
+# HELP fsm_transitions_total Transit attempts by machine, source, target and outcome.
+# TYPE fsm_transitions_total counter
+fsm_transitions_total{from="created",machine="order",outcome="ok",to="paid"} 1
+fsm_transitions_total{from="created",machine="order",outcome="rejected",to="paid"} 1
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), "fsm_transitions_total"); err != nil {
+		t.Error(err)
+	}
+	if n := testutil.CollectAndCount(metrics.guards); n != 1 {
+		t.Errorf("%d guard histograms, want created->paid only", n)
+	}
+	if n := testutil.CollectAndCount(metrics.hooks); n != 1 {
+		t.Errorf("%d hook histograms, want the enter hook of paid only", n)
+	}
+}
+
+func TestNewRegistersOnce(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	if _, err := New(registry); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(registry); err == nil {
+		t.Error("collectors registered twice")
+	}
+}
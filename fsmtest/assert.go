@@ -0,0 +1,54 @@
+package fsmtest
+
+import "strings"
+
+// TB is the part of testing.TB the assertions need.
+type TB interface {
+	Helper()
+	Errorf(format string, args ...interface{})
+}
+
+// AssertTransitionSequence checks the states entered, in order.
+func AssertTransitionSequence(t TB, r *Recorder, states ...string) bool {
+	t.Helper()
+	got := r.States()
+	if strings.Join(got, "->") != strings.Join(states, "->") {
+		t.Errorf("[fsmtest] transition sequence %v, want %v", got, states)
+		return false
+	}
+	return true
+}
+
+// AssertHookCalled checks a hook of kind (fsm.HookEnter, fsm.HookExit, ...) ran for state.
+func AssertHookCalled(t TB, r *Recorder, state, kind string) bool {
+	t.Helper()
+	for _, e := range r.Events(EventHook) {
+		if e.State == state && e.Hook == kind {
+			return true
+		}
+	}
+	t.Errorf("[fsmtest] %s hook of state %s not called", kind, state)
+	return false
+}
+
+// AssertRejected checks a transit to target was rejected with an error containing reason,
+// an empty reason matches any error.
+func AssertRejected(t TB, r *Recorder, target, reason string) bool {
+	t.Helper()
+	var errs []string
+	for _, e := range r.Events(EventReject) {
+		if e.To != target {
+			continue
+		}
+		if strings.Contains(e.Err.Error(), reason) {
+			return true
+		}
+		errs = append(errs, e.Err.Error())
+	}
+	if len(errs) == 0 {
+		t.Errorf("[fsmtest] transit to %s not rejected", target)
+	} else {
+		t.Errorf("[fsmtest] transit to %s rejected with %q, want reason %q", target, errs, reason)
+	}
+	return false
+}
@@ -0,0 +1,83 @@
+package fsmtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"strings"
+	"testing"
+)
+
+// fakeTB keeps failures instead of failing the test.
+type fakeTB struct {
+	errors []string
+}
+
+func (t *fakeTB) Helper() {}
+
+func (t *fakeTB) Errorf(format string, args ...interface{}) {
+	t.errors = append(t.errors, fmt.Sprintf(format, args...))
+}
+
+func recorded(t *testing.T) *Recorder {
+	f := newOrderFSM(NewFakeGuard(Deny()).Condition).
+		AddTransition("cancelled", "created").
+		AddStateEnterHook("cancelled", func(ctx context.Context, state string) {})
+	r := NewRecorder().Attach(f)
+	f.Transit("paid")
+	if err := f.Transit("cancelled"); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Transit("created"); err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestAssertionsPass(t *testing.T) {
+	r := recorded(t)
+	tb := &fakeTB{}
+	if !AssertTransitionSequence(tb, r, "cancelled", "created") {
+		t.Error("sequence does not pass")
+	}
+	if !AssertHookCalled(tb, r, "cancelled", fsm.HookEnter) {
+		t.Error("hook does not pass")
+	}
+	if !AssertRejected(tb, r, "paid", "condition not met") || !AssertRejected(tb, r, "paid", "") {
+		t.Error("rejection does not pass")
+	}
+	if len(tb.errors) != 0 {
+		t.Errorf("passing assertions report %q", tb.errors)
+	}
+	// a real testing.T works too
+	AssertTransitionSequence(t, r, "cancelled", "created")
+}
+
+func TestAssertionsFail(t *testing.T) {
+	r := recorded(t)
+	tests := []struct {
+		name   string
+		assert func(tb TB) bool
+		want   string
+	}{
+		{"sequence", func(tb TB) bool { return AssertTransitionSequence(tb, r, "paid") },
+			"transition sequence [cancelled created], want [paid]"},
+		{"hook state", func(tb TB) bool { return AssertHookCalled(tb, r, "paid", fsm.HookEnter) },
+			"enter hook of state paid not called"},
+		{"hook kind", func(tb TB) bool { return AssertHookCalled(tb, r, "cancelled", fsm.HookExit) },
+			"exit hook of state cancelled not called"},
+		{"not rejected", func(tb TB) bool { return AssertRejected(tb, r, "cancelled", "") },
+			"transit to cancelled not rejected"},
+		{"other reason", func(tb TB) bool { return AssertRejected(tb, r, "paid", "balance") },
+			`want reason "balance"`},
+	}
+	for _, tt := range tests {
+		tb := &fakeTB{}
+		if tt.assert(tb) {
+			t.Errorf("%s passes", tt.name)
+		}
+		if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], tt.want) {
+			t.Errorf("%s reports %q, want %q", tt.name, tb.errors, tt.want)
+		}
+	}
+}
@@ -0,0 +1,359 @@
+package fsmtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/fsmviz"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"text/tabwriter"
+)
+
+// Coverage counts hits per state, transition and condition outcome of the machines attached to it.
+// Machines are told apart by name, instances of the same definition add up.
+// It is the profile written by WriteProfile.
+type Coverage struct {
+	mu       sync.Mutex
+	Machines map[string]*MachineCoverage `json:"machines"`
+}
+
+type MachineCoverage struct {
+	States      map[string]int                 `json:"states"`
+	Transitions map[string]*TransitionCoverage `json:"transitions"`
+}
+
+// TransitionCoverage counts taken transitions and their condition outcomes,
+// a transition without condition is taken without a guard hit.
+// A *fsm.Denial is a false answer, GuardError counts the other errors.
+type TransitionCoverage struct {
+	From       string `json:"from"`
+	To         string `json:"to"`
+	Hits       int    `json:"hits"`
+	GuardTrue  int    `json:"guardTrue"`
+	GuardFalse int    `json:"guardFalse"`
+	GuardError int    `json:"guardError"`
+}
+
+func NewCoverage() *Coverage {
+	return &Coverage{Machines: make(map[string]*MachineCoverage)}
+}
+
+var defaultCoverage = NewCoverage()
+
+// Cover attaches f to the coverage shared by the test binary, see WriteCoverProfile.
+func Cover(f *fsm.FSM) *fsm.FSM {
+	defaultCoverage.Attach(f)
+	return f
+}
+
+// WriteCoverProfile writes the shared coverage as a fragment of its own in dir, call it from TestMain after m.Run.
+// Packages of go test ./... run in parallel, MergeProfiles adds up their fragments once the run is over.
+func WriteCoverProfile(dir string) error {
+	return defaultCoverage.WriteFragment(dir)
+}
+
+// Attach registers the definition of f so transitions never taken show up,
+// and counts what f does from now on. The current state counts as a hit.
+func (c *Coverage) Attach(f *fsm.FSM) *Coverage {
+	c.mu.Lock()
+	m := c.machine(f.Name())
+	for _, s := range f.States() {
+		m.States[s.Name] += 0
+	}
+	for _, t := range f.Transitions() {
+		m.transition(t.From.Name, t.To.Name)
+	}
+	if state := f.GetCurrentState(); state != "" {
+		m.States[state]++
+	}
+	c.mu.Unlock()
+	f.AddObserver(&coverageObserver{coverage: c, name: f.Name()})
+	return c
+}
+
+// machine must be called with c.mu held.
+func (c *Coverage) machine(name string) *MachineCoverage {
+	m, ok := c.Machines[name]
+	if !ok {
+		m = &MachineCoverage{States: make(map[string]int), Transitions: make(map[string]*TransitionCoverage)}
+		c.Machines[name] = m
+	}
+	return m
+}
+
+func (m *MachineCoverage) transition(from, to string) *TransitionCoverage {
+	key := fsm.GenTransitionKey(from, to)
+	t, ok := m.Transitions[key]
+	if !ok {
+		t = &TransitionCoverage{From: from, To: to}
+		m.Transitions[key] = t
+	}
+	return t
+}
+
+// Merge adds the hits of other.
+func (c *Coverage) Merge(other *Coverage) {
+	if other == c {
+		return
+	}
+	// copy other first, holding both locks could deadlock with a merge the other way
+	copied := other.clone()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for name, om := range copied.Machines {
+		m := c.machine(name)
+		for state, hits := range om.States {
+			m.States[state] += hits
+		}
+		for _, ot := range om.Transitions {
+			t := m.transition(ot.From, ot.To)
+			t.Hits += ot.Hits
+			t.GuardTrue += ot.GuardTrue
+			t.GuardFalse += ot.GuardFalse
+			t.GuardError += ot.GuardError
+		}
+	}
+}
+
+func (c *Coverage) clone() *Coverage {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	copied := NewCoverage()
+	for name, m := range c.Machines {
+		cm := copied.machine(name)
+		for state, hits := range m.States {
+			cm.States[state] = hits
+		}
+		for key, t := range m.Transitions {
+			ct := *t
+			cm.Transitions[key] = &ct
+		}
+	}
+	return copied
+}
+
+func ReadProfile(path string) (*Coverage, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCoverage()
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errors.New(fmt.Sprintf("[fsm] read coverage profile %s: %s", path, err))
+	}
+	return c, nil
+}
+
+// WriteProfile writes the coverage as a json profile to path, replacing the file.
+func (c *Coverage) WriteProfile(path string) error {
+	data, err := c.marshal()
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+// WriteFragment writes the coverage to a new profile in dir, which is created if missing.
+// Every call writes a file of its own, so processes can share dir without losing hits.
+func (c *Coverage) WriteFragment(dir string) error {
+	data, err := c.marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	// written under a temporary name first so MergeProfiles never reads half a fragment
+	file, err := ioutil.TempFile(dir, ".fragment-*")
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return err
+	}
+	return os.Rename(file.Name(), file.Name()+fragmentSuffix)
+}
+
+const fragmentSuffix = ".fsmcover"
+
+// MergeProfiles adds up the fragments in dir and removes them, so the next run starts from zero.
+func MergeProfiles(dir string) (*Coverage, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*"+fragmentSuffix))
+	if err != nil {
+		return nil, err
+	}
+	merged := NewCoverage()
+	for _, path := range paths {
+		fragment, err := ReadProfile(path)
+		if err != nil {
+			return nil, err
+		}
+		merged.Merge(fragment)
+	}
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+	return merged, nil
+}
+
+func (c *Coverage) marshal() ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return json.MarshalIndent(c, "", "  ")
+}
+
+// WriteTable prints hits of every machine, state and transition, uncovered ones are marked with !.
+func (c *Coverage) WriteTable(w io.Writer) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, name := range sortedKeys(c.Machines) {
+		m := c.Machines[name]
+		coveredStates, coveredTransitions := 0, 0
+		fmt.Fprintf(tw, "%s\n", name)
+		fmt.Fprintf(tw, "\tstate\thits\t\t\t\n")
+		for _, state := range sortedKeys(m.States) {
+			hits := m.States[state]
+			if hits > 0 {
+				coveredStates++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t\t\t\n", uncovered(hits), state, hits)
+		}
+		fmt.Fprintf(tw, "\ttransition\thits\tguard true\tguard false\tguard error\n")
+		for _, key := range sortedKeys(m.Transitions) {
+			t := m.Transitions[key]
+			if t.Hits > 0 {
+				coveredTransitions++
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%d\n",
+				uncovered(t.Hits), key, t.Hits, t.GuardTrue, t.GuardFalse, t.GuardError)
+		}
+		fmt.Fprintf(tw, "\tcovered\tstates %s\ttransitions %s\t\t\n",
+			percent(coveredStates, len(m.States)), percent(coveredTransitions, len(m.Transitions)))
+	}
+	return tw.Flush()
+}
+
+// Graph returns the definition recorded for machine, to be drawn with HeatOption.
+func (c *Coverage) Graph(machine string) *fsmviz.Graph {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	g := &fsmviz.Graph{Name: machine}
+	m, ok := c.Machines[machine]
+	if !ok {
+		return g
+	}
+	for _, state := range sortedKeys(m.States) {
+		g.States = append(g.States, fsmviz.State{Name: state})
+	}
+	for _, key := range sortedKeys(m.Transitions) {
+		t := m.Transitions[key]
+		g.Transitions = append(g.Transitions, fsmviz.Transition{From: t.From, To: t.To, Key: key})
+	}
+	return g
+}
+
+// HeatOption colors a diagram of machine by hits, e.g.
+// graphviz.Render(w, coverage.Graph("order"), fsmviz.FormatPNG, coverage.HeatOption("order"))
+func (c *Coverage) HeatOption(machine string) fsmviz.Option {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	states, transitions := make(map[string]int), make(map[string]int)
+	if m, ok := c.Machines[machine]; ok {
+		for state, hits := range m.States {
+			states[state] = hits
+		}
+		for key, t := range m.Transitions {
+			transitions[key] = t.Hits
+		}
+	}
+	return fsmviz.WithHeat(states, transitions)
+}
+
+type coverageObserver struct {
+	coverage *Coverage
+	name     string
+}
+
+func (o *coverageObserver) OnGuard(ctx context.Context, from, to string, allowed bool, err error) {
+	o.coverage.mu.Lock()
+	defer o.coverage.mu.Unlock()
+	t := o.coverage.machine(o.name).transition(from, to)
+	var denial *fsm.Denial
+	switch {
+	case errors.As(err, &denial):
+		t.GuardFalse++
+	case err != nil:
+		t.GuardError++
+	case allowed:
+		t.GuardTrue++
+	default:
+		t.GuardFalse++
+	}
+}
+
+func (o *coverageObserver) OnHook(ctx context.Context, state, kind string) {}
+
+// OnTransit counts a forced move, see fsm.Forced, as a state hit only.
+func (o *coverageObserver) OnTransit(ctx context.Context, from, to string) {
+	o.coverage.mu.Lock()
+	defer o.coverage.mu.Unlock()
+	m := o.coverage.machine(o.name)
+	m.States[to]++
+	if fsm.Forced(ctx) {
+		return
+	}
+	if t, ok := m.Transitions[fsm.GenTransitionKey(from, to)]; ok {
+		t.Hits++
+	}
+}
+
+func (o *coverageObserver) OnReject(ctx context.Context, from, to string, err error) {}
+
+func uncovered(hits int) string {
+	if hits == 0 {
+		return "!"
+	}
+	return ""
+}
+
+func percent(covered, total int) string {
+	if total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%d/%d %.1f%%", covered, total, 100*float64(covered)/float64(total))
+}
+
+func sortedKeys(m interface{}) []string {
+	var keys []string
+	switch m := m.(type) {
+	case map[string]*MachineCoverage:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]int:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	case map[string]*TransitionCoverage:
+		for k := range m {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
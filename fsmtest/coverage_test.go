@@ -0,0 +1,177 @@
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newOrderFSM(guard func(ctx context.Context, state string) (bool, error)) *fsm.FSM {
+	return fsm.NewFSM(context.Background(), "order").
+		AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransitionOn("created", "paid", guard).
+		AddTransition("created", "cancelled")
+}
+
+func TestCoverageCounts(t *testing.T) {
+	c := NewCoverage()
+	guard := NewFakeGuard(Deny(), DenyWith("balance", "not enough"), Fail(errors.New("db")), Allow())
+	f := newOrderFSM(guard.Condition)
+	c.Attach(f)
+	for i := 0; i < 4; i++ {
+		f.Transit("paid")
+	}
+
+	m := c.Machines["order"]
+	paid := m.Transitions[fsm.GenTransitionKey("created", "paid")]
+	if paid.Hits != 1 || paid.GuardTrue != 1 || paid.GuardFalse != 2 || paid.GuardError != 1 {
+		t.Errorf("created->paid coverage %+v", *paid)
+	}
+	if cancelled := m.Transitions[fsm.GenTransitionKey("created", "cancelled")]; cancelled.Hits != 0 {
+		t.Errorf("created->cancelled coverage %+v", *cancelled)
+	}
+	if m.States["created"] != 1 || m.States["paid"] != 1 || m.States["cancelled"] != 0 {
+		t.Errorf("state hits %v", m.States)
+	}
+
+	var table strings.Builder
+	if err := c.WriteTable(&table); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(table.String(), "!  created->cancelled") {
+		t.Errorf("uncovered transition is not marked:\n%s", table.String())
+	}
+}
+
+func TestCoverageFragments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsmcover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// like test binaries of several packages writing at the same time
+	const writers = 8
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c := NewCoverage()
+			f := newOrderFSM(nil)
+			c.Attach(f)
+			f.Transit("paid")
+			errs <- c.WriteFragment(dir)
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	merged, err := MergeProfiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := merged.Machines["order"].Transitions[fsm.GenTransitionKey("created", "paid")].Hits; hits != writers {
+		t.Errorf("merged hits %d, want %d", hits, writers)
+	}
+
+	// merging consumed the fragments, a new run starts from zero
+	again, err := MergeProfiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Machines) != 0 {
+		t.Errorf("second merge found %v", again.Machines)
+	}
+}
+
+func TestCoverageWriteProfileReplaces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsmcover")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "fsm.cover")
+
+	c := NewCoverage()
+	f := newOrderFSM(nil)
+	c.Attach(f)
+	f.Transit("paid")
+	for i := 0; i < 2; i++ {
+		if err := c.WriteProfile(path); err != nil {
+			t.Fatal(err)
+		}
+	}
+	read, err := ReadProfile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hits := read.Machines["order"].Transitions[fsm.GenTransitionKey("created", "paid")].Hits; hits != 1 {
+		t.Errorf("profile hits %d after writing twice, want 1", hits)
+	}
+}
+
+func TestCoverageSkipsForcedMoves(t *testing.T) {
+	c := NewCoverage()
+	f := newOrderFSM(nil)
+	c.Attach(f)
+	if err := f.SetState("paid"); err != nil {
+		t.Fatal(err)
+	}
+	m := c.Machines["order"]
+	if paid := m.Transitions[fsm.GenTransitionKey("created", "paid")]; paid.Hits != 0 {
+		t.Errorf("forced move counted as a transition hit %+v", *paid)
+	}
+	if m.States["paid"] != 1 {
+		t.Errorf("state hits %v", m.States)
+	}
+}
+
+func TestCoverageMergeBothWays(t *testing.T) {
+	a, b := NewCoverage(), NewCoverage()
+	fa, fb := newOrderFSM(nil), newOrderFSM(nil)
+	a.Attach(fa)
+	b.Attach(fb)
+	fa.Transit("paid")
+	fb.Transit("cancelled")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.Merge(b)
+		}()
+		go func() {
+			defer wg.Done()
+			b.Merge(a)
+		}()
+	}
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("merges in opposite directions deadlock")
+	}
+	if a.Machines["order"].States["cancelled"] == 0 || b.Machines["order"].States["paid"] == 0 {
+		t.Errorf("merge lost hits: %v, %v", a.Machines["order"].States, b.Machines["order"].States)
+	}
+}
@@ -0,0 +1,8 @@
+package fsmtest
+
+/*
+fsmtest helps testing code built on fsm.FSM.
+A Recorder observes an instance and keeps every condition evaluation, hook call,
+transit and rejection in order, the Assert helpers check it against a testing.T.
+FakeGuard answers conditions from a script and FakeClock gives recorded events a controlled time.
+*/
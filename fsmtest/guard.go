@@ -0,0 +1,62 @@
+package fsmtest
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"sync"
+)
+
+// Answer is one scripted result of a FakeGuard.
+type Answer struct {
+	Allow bool
+	Err   error
+}
+
+func Allow() Answer { return Answer{Allow: true} }
+
+func Deny() Answer { return Answer{} }
+
+func Fail(err error) Answer { return Answer{Err: err} }
+
+// DenyWith denies with a reason, see fsm.Deny.
+func DenyWith(code, message string) Answer { return Answer{Err: fsm.Deny(code, message, nil)} }
+
+// FakeGuard answers conditions from a script, the last answer repeats once the script is used up.
+// An empty script allows everything.
+type FakeGuard struct {
+	mu      sync.Mutex
+	answers []Answer
+	states  []string
+}
+
+func NewFakeGuard(answers ...Answer) *FakeGuard {
+	return &FakeGuard{answers: answers}
+}
+
+// Condition is passed to fsm.AddTransitionOn.
+func (g *FakeGuard) Condition(ctx context.Context, state string) (bool, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.states = append(g.states, state)
+	if len(g.answers) == 0 {
+		return true, nil
+	}
+	answer := g.answers[0]
+	if len(g.answers) > 1 {
+		g.answers = g.answers[1:]
+	}
+	return answer.Allow, answer.Err
+}
+
+func (g *FakeGuard) Calls() int {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return len(g.states)
+}
+
+// States returns the current state of every call.
+func (g *FakeGuard) States() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return append([]string(nil), g.states...)
+}
@@ -0,0 +1,83 @@
+package fsmtest
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryMetrics is a fsm.Metrics keeping everything in memory for assertions.
+type MemoryMetrics struct {
+	mu          sync.Mutex
+	transitions map[string]int
+	guards      map[string][]time.Duration
+	hooks       map[string][]time.Duration
+	instances   map[string]float64
+}
+
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{
+		transitions: make(map[string]int),
+		guards:      make(map[string][]time.Duration),
+		hooks:       make(map[string][]time.Duration),
+		instances:   make(map[string]float64),
+	}
+}
+
+func metricKey(labels ...string) string {
+	return strings.Join(labels, "|")
+}
+
+func (m *MemoryMetrics) IncTransition(machine, from, to, outcome string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.transitions[metricKey(machine, from, to, outcome)]++
+}
+
+func (m *MemoryMetrics) ObserveGuard(machine, from, to string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricKey(machine, from, to)
+	m.guards[key] = append(m.guards[key], duration)
+}
+
+func (m *MemoryMetrics) ObserveHook(machine, state, kind string, duration time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := metricKey(machine, state, kind)
+	m.hooks[key] = append(m.hooks[key], duration)
+}
+
+func (m *MemoryMetrics) AddInstances(machine, state string, delta float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instances[metricKey(machine, state)] += delta
+}
+
+// Transitions returns the count of transit attempts with the given outcome.
+func (m *MemoryMetrics) Transitions(machine, from, to, outcome string) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.transitions[metricKey(machine, from, to, outcome)]
+}
+
+// GuardDurations returns every observed condition duration of a transition.
+func (m *MemoryMetrics) GuardDurations(machine, from, to string) []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.guards[metricKey(machine, from, to)]...)
+}
+
+// HookDurations returns every observed hook duration of a state and hook kind.
+func (m *MemoryMetrics) HookDurations(machine, state, kind string) []time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]time.Duration(nil), m.hooks[metricKey(machine, state, kind)]...)
+}
+
+// Instances returns the number of live instances in state.
+func (m *MemoryMetrics) Instances(machine, state string) float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.instances[metricKey(machine, state)]
+}
@@ -0,0 +1,126 @@
+package fsmtest
+
+import (
+	"context"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"sync"
+	"time"
+)
+
+type EventKind string
+
+const (
+	EventGuard   EventKind = "guard"
+	EventHook    EventKind = "hook"
+	EventTransit EventKind = "transit"
+	EventReject  EventKind = "reject"
+)
+
+// Event is one thing an observed instance did.
+// From and To are set for guard, transit and reject events, State and Hook for hook events.
+type Event struct {
+	Kind    EventKind
+	At      time.Time
+	From    string
+	To      string
+	State   string
+	Hook    string
+	Allowed bool
+	Err     error
+}
+
+func (e Event) String() string {
+	switch e.Kind {
+	case EventGuard:
+		return fmt.Sprintf("guard %s->%s allowed=%v err=%v", e.From, e.To, e.Allowed, e.Err)
+	case EventHook:
+		return fmt.Sprintf("hook %s %s", e.Hook, e.State)
+	case EventReject:
+		return fmt.Sprintf("reject %s->%s: %v", e.From, e.To, e.Err)
+	default:
+		return fmt.Sprintf("transit %s->%s", e.From, e.To)
+	}
+}
+
+// Recorder is a fsm.Observer keeping events in order.
+type Recorder struct {
+	mu     sync.Mutex
+	clock  Clock
+	events []Event
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{clock: realClock{}}
+}
+
+func (r *Recorder) WithClock(clock Clock) *Recorder {
+	r.clock = clock
+	return r
+}
+
+// Attach starts observing f.
+func (r *Recorder) Attach(f *fsm.FSM) *Recorder {
+	f.AddObserver(r)
+	return r
+}
+
+func (r *Recorder) OnGuard(ctx context.Context, from, to string, allowed bool, err error) {
+	r.record(Event{Kind: EventGuard, From: from, To: to, Allowed: allowed, Err: err})
+}
+
+func (r *Recorder) OnHook(ctx context.Context, state, kind string) {
+	r.record(Event{Kind: EventHook, State: state, Hook: kind})
+}
+
+func (r *Recorder) OnTransit(ctx context.Context, from, to string) {
+	r.record(Event{Kind: EventTransit, From: from, To: to})
+}
+
+func (r *Recorder) OnReject(ctx context.Context, from, to string, err error) {
+	r.record(Event{Kind: EventReject, From: from, To: to, Err: err})
+}
+
+func (r *Recorder) record(e Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	e.At = r.clock.Now()
+	r.events = append(r.events, e)
+}
+
+// Events returns recorded events, of the given kinds if any.
+func (r *Recorder) Events(kinds ...EventKind) []Event {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	events := make([]Event, 0, len(r.events))
+	for _, e := range r.events {
+		if len(kinds) == 0 || hasKind(kinds, e.Kind) {
+			events = append(events, e)
+		}
+	}
+	return events
+}
+
+// States returns the states entered in order.
+func (r *Recorder) States() []string {
+	states := make([]string, 0)
+	for _, e := range r.Events(EventTransit) {
+		states = append(states, e.To)
+	}
+	return states
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = nil
+}
+
+func hasKind(kinds []EventKind, kind EventKind) bool {
+	for _, k := range kinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,108 @@
+package fsmtest
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func eventStrings(events []Event) []string {
+	var s []string
+	for _, e := range events {
+		s = append(s, e.String())
+	}
+	return s
+}
+
+func TestRecorderEvents(t *testing.T) {
+	guard := NewFakeGuard(Deny(), Allow())
+	f := newOrderFSM(guard.Condition).
+		AddStateExitHook("created", func(ctx context.Context, state string) {}).
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {})
+	r := NewRecorder().Attach(f)
+	f.Transit("paid")
+	f.Transit("paid")
+
+	want := []string{
+		"guard created->paid allowed=false err=<nil>",
+		"reject created->paid: [fsm] denied condition_not_met: transit(created->paid) condition not met",
+		"guard created->paid allowed=true err=<nil>",
+		"hook exit created",
+		"hook enter paid",
+		"transit created->paid",
+	}
+	if got := eventStrings(r.Events()); !reflect.DeepEqual(got, want) {
+		t.Errorf("events %q\nwant %q", got, want)
+	}
+	if got := eventStrings(r.Events(EventGuard, EventTransit)); len(got) != 3 || got[2] != "transit created->paid" {
+		t.Errorf("guard and transit events %q", got)
+	}
+	if got := r.States(); !reflect.DeepEqual(got, []string{"paid"}) {
+		t.Errorf("states %v", got)
+	}
+
+	r.Reset()
+	if len(r.Events()) != 0 || len(r.States()) != 0 {
+		t.Errorf("events after Reset %v", r.Events())
+	}
+}
+
+func TestRecorderFakeClock(t *testing.T) {
+	start := time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)
+	clock := NewFakeClock(start)
+	f := newOrderFSM(nil)
+	r := NewRecorder().WithClock(clock).Attach(f)
+	f.Transit("paid")
+	clock.Advance(time.Minute)
+	f.Transit("cancelled")
+	clock.Set(start.Add(time.Hour))
+	f.SetState("created")
+
+	events := r.Events(EventTransit, EventReject)
+	if len(events) != 3 {
+		t.Fatalf("events %v", events)
+	}
+	for i, want := range []time.Time{start, start.Add(time.Minute), start.Add(time.Hour)} {
+		if !events[i].At.Equal(want) {
+			t.Errorf("%s at %s, want %s", events[i], events[i].At, want)
+		}
+	}
+	if clock.Now() != start.Add(time.Hour) {
+		t.Errorf("clock moved by itself to %s", clock.Now())
+	}
+}
+
+func TestFakeGuardScript(t *testing.T) {
+	db := errors.New("db")
+	guard := NewFakeGuard(Allow(), Fail(db), Deny())
+	ctx := context.Background()
+	want := []Answer{Allow(), Fail(db), Deny(), Deny(), Deny()}
+	for i, answer := range want {
+		ok, err := guard.Condition(ctx, "created")
+		if ok != answer.Allow || err != answer.Err {
+			t.Errorf("call %d answers %v, %v, want %+v", i, ok, err, answer)
+		}
+	}
+	if guard.Calls() != len(want) {
+		t.Errorf("%d calls", guard.Calls())
+	}
+
+	empty := NewFakeGuard()
+	if ok, err := empty.Condition(ctx, "paid"); !ok || err != nil {
+		t.Errorf("empty script answers %v, %v", ok, err)
+	}
+	if got := empty.States(); !reflect.DeepEqual(got, []string{"paid"}) {
+		t.Errorf("states %v", got)
+	}
+}
+
+func TestFakeGuardDenyWith(t *testing.T) {
+	ok, err := NewFakeGuard(DenyWith("balance", "not enough")).Condition(context.Background(), "created")
+	var denial *fsm.Denial
+	if ok || !errors.As(err, &denial) || denial.Code != "balance" {
+		t.Errorf("DenyWith answers %v, %v", ok, err)
+	}
+}
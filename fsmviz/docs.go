@@ -0,0 +1,8 @@
+package fsmviz
+
+/*
+fsmviz draws fsm and singletonfsm definitions.
+It is pure go and renders dot and svg on its own,
+png and jpg are rendered by the cgo based fsmviz/graphviz subpackage.
+Import it only where diagrams are needed, fsm itself has no dependencies.
+*/
@@ -0,0 +1,115 @@
+package fsmviz
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// writeDOT writes the graph in graphviz dot language, without layout.
+func writeDOT(w io.Writer, sg *styledGraph) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "digraph %s {\n", quote(sg.name))
+	var graphAttrs []string
+	if sg.rankDir != "" {
+		graphAttrs = append(graphAttrs, attr("rankdir", string(sg.rankDir)))
+	}
+	if sg.theme.Background != "" {
+		graphAttrs = append(graphAttrs, attr("bgcolor", sg.theme.Background))
+	}
+	if sg.theme.FontName != "" {
+		graphAttrs = append(graphAttrs, attr("fontname", sg.theme.FontName))
+	}
+	if sg.caption != "" {
+		graphAttrs = append(graphAttrs, attr("label", sg.caption), attr("labelloc", "b"))
+		if sg.theme.FontColor != "" {
+			graphAttrs = append(graphAttrs, attr("fontcolor", sg.theme.FontColor))
+		}
+	}
+	if len(graphAttrs) > 0 {
+		fmt.Fprintf(bw, "\tgraph [%s];\n", strings.Join(graphAttrs, ", "))
+	}
+	if sg.theme.FontName != "" {
+		fmt.Fprintf(bw, "\tnode [%s];\n", attr("fontname", sg.theme.FontName))
+		fmt.Fprintf(bw, "\tedge [%s];\n", attr("fontname", sg.theme.FontName))
+	}
+
+	for _, cluster := range sg.clusters {
+		fmt.Fprintf(bw, "\tsubgraph %s {\n", quote("cluster_"+cluster))
+		clusterAttrs := []string{attr("label", cluster)}
+		if sg.theme.FontColor != "" {
+			clusterAttrs = append(clusterAttrs, attr("fontcolor", sg.theme.FontColor))
+		}
+		if sg.theme.NodeColor != "" {
+			clusterAttrs = append(clusterAttrs, attr("color", sg.theme.NodeColor))
+		}
+		fmt.Fprintf(bw, "\t\tgraph [%s];\n", strings.Join(clusterAttrs, ", "))
+		for _, node := range sg.nodes {
+			if node.cluster == cluster {
+				writeDOTNode(bw, "\t\t", node)
+			}
+		}
+		fmt.Fprint(bw, "\t}\n")
+	}
+	for _, node := range sg.nodes {
+		if node.cluster == "" {
+			writeDOTNode(bw, "\t", node)
+		}
+	}
+
+	for _, edge := range sg.edges {
+		attrs := []string{attr("key", edge.key)}
+		attrs = appendAttr(attrs, "label", edge.label)
+		attrs = appendAttr(attrs, "tooltip", edge.tooltip)
+		attrs = appendAttr(attrs, "color", edge.color)
+		attrs = appendAttr(attrs, "fontcolor", edge.fontColor)
+		if edge.dashed {
+			attrs = append(attrs, attr("style", "dashed"))
+		}
+		if edge.penWidth > 0 {
+			attrs = append(attrs, attr("penwidth", strconv.FormatFloat(edge.penWidth, 'f', -1, 64)))
+		}
+		fmt.Fprintf(bw, "\t%s -> %s [%s];\n", quote(edge.from), quote(edge.to), strings.Join(attrs, ", "))
+	}
+	fmt.Fprint(bw, "}\n")
+	return bw.Flush()
+}
+
+func writeDOTNode(w io.Writer, indent string, node styledNode) {
+	var attrs []string
+	if node.shape == "point" {
+		attrs = append(attrs, attr("label", ""))
+	}
+	if node.label != node.name && node.label != "" {
+		attrs = append(attrs, attr("label", node.label))
+	}
+	attrs = appendAttr(attrs, "tooltip", node.tooltip)
+	attrs = appendAttr(attrs, "shape", node.shape)
+	attrs = appendAttr(attrs, "color", node.color)
+	attrs = appendAttr(attrs, "fontcolor", node.fontColor)
+	if node.fillColor != "" {
+		attrs = append(attrs, attr("style", "filled"), attr("fillcolor", node.fillColor))
+	}
+	if len(attrs) == 0 {
+		fmt.Fprintf(w, "%s%s;\n", indent, quote(node.name))
+		return
+	}
+	fmt.Fprintf(w, "%s%s [%s];\n", indent, quote(node.name), strings.Join(attrs, ", "))
+}
+
+func appendAttr(attrs []string, name, value string) []string {
+	if value == "" {
+		return attrs
+	}
+	return append(attrs, attr(name, value))
+}
+
+func attr(name, value string) string {
+	return name + "=" + quote(value)
+}
+
+func quote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
@@ -0,0 +1,255 @@
+package fsmviz
+
+import (
+	"context"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/singletonfsm"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// TagTerminal marks a state as terminal, it is drawn as a double circle like final states.
+const TagTerminal = fsm.TagTerminal
+
+// Graph is a snapshot of a machine definition, independent of the renderer.
+// It marshals to json as the exported definition used by RenderHTML.
+type Graph struct {
+	Name        string       `json:"name"`
+	Initial     string       `json:"initial,omitempty"`
+	States      []State      `json:"states"`
+	Transitions []Transition `json:"transitions"`
+}
+
+type State struct {
+	Name        string   `json:"name"`
+	Label       string   `json:"label,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	Shape       string   `json:"shape,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Final       bool     `json:"final,omitempty"`
+	EnterHooks  []string `json:"enterHooks,omitempty"`
+	ExitHooks   []string `json:"exitHooks,omitempty"`
+}
+
+type Transition struct {
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Key         string   `json:"key"`
+	Guard       string   `json:"guard,omitempty"`
+	Actions     []string `json:"actions,omitempty"`
+	Label       string   `json:"label,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Color       string   `json:"color,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+}
+
+// FromFSM takes a snapshot of the definition of f.
+func FromFSM(f *fsm.FSM) *Graph {
+	g := &Graph{Name: f.Name(), Initial: f.GetInitialState()}
+	for _, s := range f.States() {
+		g.States = append(g.States, State{
+			Name:        s.Name,
+			Label:       s.Meta.Label,
+			Description: s.Meta.Description,
+			Color:       s.Meta.Color,
+			Shape:       s.Meta.Shape,
+			Tags:        s.Meta.Tags,
+			Final:       s.IsFinal(),
+			EnterHooks:  fsmHookNames(s.EnterHooks()),
+			ExitHooks:   fsmHookNames(s.ExitHooks()),
+		})
+	}
+	for _, t := range f.Transitions() {
+		g.Transitions = append(g.Transitions, Transition{
+			From:        t.From.Name,
+			To:          t.To.Name,
+			Key:         t.Key,
+			Guard:       guardName(t),
+			Actions:     actionNames(t.Actions),
+			Label:       t.Meta.Label,
+			Description: t.Meta.Description,
+			Color:       t.Meta.Color,
+			Tags:        t.Meta.Tags,
+		})
+	}
+	return g
+}
+
+// FromSingletonFSM takes a snapshot of the definition of f.
+func FromSingletonFSM(f *singletonfsm.FSM) *Graph {
+	g := &Graph{Name: f.Name(), Initial: f.GetInitialState()}
+	for _, s := range f.States() {
+		g.States = append(g.States, State{
+			Name:        s.Name,
+			Label:       s.Meta.Label,
+			Description: s.Meta.Description,
+			Color:       s.Meta.Color,
+			Shape:       s.Meta.Shape,
+			Tags:        s.Meta.Tags,
+			Final:       s.IsFinal(),
+			EnterHooks:  singletonHookNames(s.EnterHooks()),
+			ExitHooks:   singletonHookNames(s.ExitHooks()),
+		})
+	}
+	for _, t := range f.Transitions() {
+		g.Transitions = append(g.Transitions, Transition{
+			From:        t.From.Name,
+			To:          t.To.Name,
+			Key:         t.Key,
+			Guard:       funcName(t.Condition),
+			Actions:     actionNames(t.Actions),
+			Label:       t.Meta.Label,
+			Description: t.Meta.Description,
+			Color:       t.Meta.Color,
+			Tags:        t.Meta.Tags,
+		})
+	}
+	return g
+}
+
+func (s *State) HasTag(tag string) bool {
+	for _, t := range s.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayLabel is the label if set, otherwise the name.
+func (s *State) DisplayLabel() string {
+	if s.Label != "" {
+		return s.Label
+	}
+	return s.Name
+}
+
+// DisplayLabel formats as `event [guard] / action`, any part may be absent.
+func (t *Transition) DisplayLabel() string {
+	var parts []string
+	if t.Label != "" {
+		parts = append(parts, t.Label)
+	}
+	if t.Guard != "" {
+		parts = append(parts, fmt.Sprintf("[%s]", t.Guard))
+	}
+	if len(t.Actions) > 0 {
+		parts = append(parts, "/ "+strings.Join(t.Actions, ", "))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (g *Graph) hasState(name string) bool {
+	for _, s := range g.States {
+		if s.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachable returns states reachable from the given state, following transition links only.
+func (g *Graph) Reachable(from string) map[string]bool {
+	reachable := map[string]bool{from: true}
+	queue := []string{from}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+		for _, t := range g.Transitions {
+			if t.From == state && !reachable[t.To] {
+				reachable[t.To] = true
+				queue = append(queue, t.To)
+			}
+		}
+	}
+	return reachable
+}
+
+// PathSteps maps transition keys of a visited path to their step numbers.
+func (g *Graph) PathSteps(path []string) (map[string][]string, error) {
+	steps := make(map[string][]string)
+	for i, state := range path {
+		if !g.hasState(state) {
+			return nil, fmt.Errorf("[fsm] state not defined %s", state)
+		}
+		if i > 0 {
+			key := fsm.GenTransitionKey(path[i-1], state)
+			steps[key] = append(steps[key], strconv.Itoa(i))
+		}
+	}
+	return steps, nil
+}
+
+// ForcedSteps returns steps of a path that have no transition, such as a forced SetState.
+func (g *Graph) ForcedSteps(path []string) []Transition {
+	keys := make(map[string]bool)
+	for _, t := range g.Transitions {
+		keys[t.Key] = true
+	}
+	var forced []Transition
+	for i := 1; i < len(path); i++ {
+		key := fsm.GenTransitionKey(path[i-1], path[i])
+		if !keys[key] {
+			keys[key] = true
+			forced = append(forced, Transition{From: path[i-1], To: path[i], Key: key})
+		}
+	}
+	return forced
+}
+
+// guardName is the name of the guard given to AddTransitionGuard, e.g. a combined condition, or the function name.
+func guardName(t *fsm.Transition) string {
+	if t.ConditionName != "" {
+		return t.ConditionName
+	}
+	return funcName(t.Condition)
+}
+
+func fsmHookNames(hooks []*fsm.Hook) []string {
+	var names []string
+	for _, h := range hooks {
+		names = append(names, hookName(h.Name, h.Func, h.FromStates))
+	}
+	return names
+}
+
+func singletonHookNames(hooks []*singletonfsm.Hook) []string {
+	var names []string
+	for _, h := range hooks {
+		names = append(names, hookName(h.Name, h.Func, h.FromStates))
+	}
+	return names
+}
+
+func actionNames(actions []func(ctx context.Context, from, to string) error) []string {
+	var names []string
+	for _, action := range actions {
+		names = append(names, funcName(action))
+	}
+	return names
+}
+
+// hookName is the hook name or its function name, with the states it is limited to.
+func hookName(name string, hook interface{}, from []string) string {
+	if name == "" {
+		name = funcName(hook)
+	}
+	if len(from) > 0 {
+		name += " (from " + strings.Join(from, ", ") + ")"
+	}
+	return name
+}
+
+func funcName(i interface{}) string {
+	if reflect.ValueOf(i).IsNil() {
+		return ""
+	}
+	fullName := runtime.FuncForPC(reflect.ValueOf(i).Pointer()).Name()
+	names := strings.Split(fullName, ".")
+	// method values are suffixed with -fm
+	return strings.TrimSuffix(names[len(names)-1], "-fm")
+}
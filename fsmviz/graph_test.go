@@ -0,0 +1,24 @@
+package fsmviz
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"testing"
+)
+
+func TestFromFSMGuardNames(t *testing.T) {
+	f := fsm.NewFSM(context.Background(), "order").AddStates("created", "paid", "cancelled").
+		SetInitial("created").
+		AddTransitionGuard("created", "paid", fsm.And(fsm.Condition(isPhysical), fsm.Not(fsm.HasRole("guest")))).
+		AddTransitionOn("created", "cancelled", isPhysical)
+	guards := make(map[string]string)
+	for _, transition := range FromFSM(f).Transitions {
+		guards[transition.To] = transition.Guard
+	}
+	if want := "isPhysical && !(role in (guest))"; guards["paid"] != want {
+		t.Errorf("created->paid guard %q, want %q", guards["paid"], want)
+	}
+	if guards["cancelled"] != "isPhysical" {
+		t.Errorf("created->cancelled guard %q", guards["cancelled"])
+	}
+}
@@ -0,0 +1,113 @@
+package graphviz
+
+import (
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsmviz"
+	"image"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const framePad = 0.4
+
+// RenderFrames replays steps on the diagram of g, one image per frame.
+// The first frame shows the state before the first step, every next frame
+// highlights the state entered and the transition just taken, captioned with its time.
+func RenderFrames(g *fsmviz.Graph, steps []fsmviz.Step, frame func(i int, img image.Image) error, opts ...fsmviz.Option) error {
+	if len(steps) == 0 {
+		return fmt.Errorf("[fsm] no step to replay")
+	}
+	first := append(opts[:len(opts):len(opts)],
+		fsmviz.WithCurrentState(steps[0].From),
+		fsmviz.WithCaption(fmt.Sprintf("start at %s\n%s", steps[0].From, formatTime(steps[0].At))))
+	img, err := renderImage(g, first)
+	if err != nil {
+		return err
+	}
+	if err := frame(0, img); err != nil {
+		return err
+	}
+	for i, step := range steps {
+		stepOpts := append(opts[:len(opts):len(opts)],
+			fsmviz.WithCurrentState(step.To),
+			fsmviz.WithActiveTransition(step.From, step.To),
+			fsmviz.WithCaption(fmt.Sprintf("%d. %s -> %s\n%s", i+1, step.From, step.To, formatTime(step.At))))
+		img, err := renderImage(g, stepOpts)
+		if err != nil {
+			return err
+		}
+		if err := frame(i+1, img); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderGIF replays steps as an animated gif, showing each frame for delay.
+func RenderGIF(w io.Writer, g *fsmviz.Graph, steps []fsmviz.Step, delay time.Duration, opts ...fsmviz.Option) error {
+	anim := &gif.GIF{}
+	err := RenderFrames(g, steps, func(i int, img image.Image) error {
+		bounds := img.Bounds()
+		paletted := image.NewPaletted(bounds, palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, bounds, img, bounds.Min)
+		anim.Image = append(anim.Image, paletted)
+		anim.Delay = append(anim.Delay, int(delay/(10*time.Millisecond)))
+		if bounds.Dx() > anim.Config.Width {
+			anim.Config.Width = bounds.Dx()
+		}
+		if bounds.Dy() > anim.Config.Height {
+			anim.Config.Height = bounds.Dy()
+		}
+		return nil
+	}, opts...)
+	if err != nil {
+		return err
+	}
+	anim.Config.ColorModel = anim.Image[0].Palette
+	return gif.EncodeAll(w, anim)
+}
+
+// WriteFrames replays steps as numbered png files in dir: frame_000.png, frame_001.png...
+func WriteFrames(dir string, g *fsmviz.Graph, steps []fsmviz.Step, opts ...fsmviz.Option) error {
+	return RenderFrames(g, steps, func(i int, img image.Image) error {
+		file, err := os.Create(filepath.Join(dir, fmt.Sprintf("frame_%03d.png", i)))
+		if err != nil {
+			return err
+		}
+		if err := png.Encode(file, img); err != nil {
+			file.Close()
+			return err
+		}
+		return file.Close()
+	}, opts...)
+}
+
+func renderImage(g *fsmviz.Graph, opts []fsmviz.Option) (img image.Image, err error) {
+	gv, graph, err := build(g, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if closeErr := graph.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		gv.Close()
+	}()
+	// the image renderer draws text wider than graphviz measures it,
+	// pad keeps the caption from being clipped
+	graph.SetPad(framePad)
+	return gv.RenderImage(graph)
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
@@ -0,0 +1,96 @@
+package graphviz
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"github.com/FingerLiu/go-fsm/fsmviz"
+	"image"
+	"image/gif"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func orderGraph() *fsmviz.Graph {
+	f := fsm.NewFSM(context.Background(), "order").
+		AddStates("created", "paid", "delivered").
+		SetInitial("created").
+		AddTransition("created", "paid").
+		AddTransition("paid", "delivered")
+	return fsmviz.FromFSM(f)
+}
+
+var steps = []fsmviz.Step{
+	{From: "created", To: "paid", At: time.Date(2022, 9, 1, 10, 0, 0, 0, time.UTC)},
+	{From: "paid", To: "delivered", At: time.Date(2022, 9, 2, 10, 0, 0, 0, time.UTC)},
+}
+
+func TestRenderFrames(t *testing.T) {
+	var frames []int
+	err := RenderFrames(orderGraph(), steps, func(i int, img image.Image) error {
+		if img.Bounds().Empty() {
+			t.Errorf("frame %d is empty", i)
+		}
+		frames = append(frames, i)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(frames) != "[0 1 2]" {
+		t.Errorf("frames %v, want one per step and the start", frames)
+	}
+}
+
+func TestRenderFramesNoStep(t *testing.T) {
+	called := false
+	err := RenderFrames(orderGraph(), nil, func(i int, img image.Image) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("no step renders frames, error %v", err)
+	}
+	if err := RenderGIF(&bytes.Buffer{}, orderGraph(), nil, time.Second); err == nil {
+		t.Error("gif of no step")
+	}
+}
+
+func TestRenderGIF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := RenderGIF(&buf, orderGraph(), steps, 500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	anim, err := gif.DecodeAll(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(anim.Image) != len(steps)+1 {
+		t.Errorf("%d frames, want %d", len(anim.Image), len(steps)+1)
+	}
+	for i, delay := range anim.Delay {
+		if delay != 50 {
+			t.Errorf("frame %d delay %d, want 50", i, delay)
+		}
+	}
+}
+
+func TestWriteFrames(t *testing.T) {
+	dir := t.TempDir()
+	if err := WriteFrames(dir, orderGraph(), steps); err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "frame_*.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != len(steps)+1 {
+		t.Errorf("frames %v", files)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "frame_002.png")); err != nil {
+		t.Error(err)
+	}
+}
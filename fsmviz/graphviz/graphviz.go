@@ -0,0 +1,54 @@
+package graphviz
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsmviz"
+	"github.com/goccy/go-graphviz"
+	"github.com/goccy/go-graphviz/cgraph"
+	"io"
+)
+
+var formats = map[fsmviz.Format]graphviz.Format{
+	fsmviz.FormatDOT: graphviz.XDOT,
+	fsmviz.FormatSVG: graphviz.SVG,
+	fsmviz.FormatPNG: graphviz.PNG,
+	fsmviz.FormatJPG: graphviz.JPG,
+}
+
+// Render lays out the diagram of g with graphviz and writes it to w in one pass.
+// Dot output carries graphviz layout positions.
+func Render(w io.Writer, g *fsmviz.Graph, format fsmviz.Format, opts ...fsmviz.Option) (err error) {
+	gvFormat, ok := formats[format]
+	if !ok {
+		return fmt.Errorf("[fsm] unsupported render format %q", format)
+	}
+	gv, graph, err := build(g, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if closeErr := graph.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+		gv.Close()
+	}()
+	return gv.Render(graph, gvFormat, w)
+}
+
+// build parses the dot written by fsmviz, so both renderers share styling.
+func build(g *fsmviz.Graph, opts []fsmviz.Option) (*graphviz.Graphviz, *cgraph.Graph, error) {
+	var dot bytes.Buffer
+	if err := fsmviz.WriteDOT(&dot, g, opts...); err != nil {
+		return nil, nil, err
+	}
+	graph, err := graphviz.ParseBytes(dot.Bytes())
+	if err != nil {
+		return nil, nil, err
+	}
+	gv := graphviz.New()
+	if o := fsmviz.NewOptions(opts...); o.Layout != "" {
+		gv.SetLayout(graphviz.Layout(o.Layout))
+	}
+	return gv, graph, nil
+}
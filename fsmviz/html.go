@@ -0,0 +1,159 @@
+package fsmviz
+
+import (
+	"bytes"
+	"html/template"
+	"io"
+)
+
+// RenderHTML writes a self-contained html page of g: the svg diagram,
+// a panel describing the clicked state and a step-through simulator.
+// The page embeds g as json and needs no network access.
+func RenderHTML(w io.Writer, g *Graph, opts ...Option) error {
+	var svg bytes.Buffer
+	if err := Render(&svg, g, FormatSVG, opts...); err != nil {
+		return err
+	}
+	return htmlTemplate.Execute(w, struct {
+		Graph *Graph
+		SVG   template.HTML
+	}{g, template.HTML(svg.String())})
+}
+
+var htmlTemplate = template.Must(template.New("fsm").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Graph.Name}}</title>
+<style>
+body { display: flex; margin: 0; font-family: Helvetica, Arial, sans-serif; font-size: 14px; }
+#diagram { flex: 1; overflow: auto; padding: 16px; }
+#panel { width: 320px; padding: 16px; border-left: 1px solid #ddd; overflow: auto; height: 100vh; box-sizing: border-box; }
+#panel h2 { margin-top: 0; }
+#panel ul { padding-left: 18px; }
+#panel button { margin: 2px 4px 2px 0; }
+.muted { color: #888; }
+.node { cursor: pointer; }
+.node.selected ellipse, .node.selected rect, .node.selected circle { stroke-width: 3; }
+.node.current ellipse, .node.current rect, .node.current circle { fill: #add8e6; }
+.edge.taken path { stroke: red; stroke-width: 2; }
+.edge.taken polygon { fill: red; stroke: red; }
+</style>
+</head>
+<body>
+<div id="diagram">{{.SVG}}</div>
+<div id="panel"><h2>{{.Graph.Name}}</h2><p class="muted">Click a state for details.</p></div>
+<script>
+(function () {
+	var definition = {{.Graph}};
+	var panel = document.getElementById("panel");
+	var history = [];
+
+	function stateOf(name) {
+		return definition.states.filter(function (s) { return s.name === name; })[0];
+	}
+	function outgoing(name) {
+		return (definition.transitions || []).filter(function (t) { return t.from === name; });
+	}
+	function el(tag, text) {
+		var e = document.createElement(tag);
+		if (text !== undefined) { e.textContent = text; }
+		return e;
+	}
+	function list(title, items) {
+		panel.appendChild(el("h3", title));
+		if (!items || items.length === 0) {
+			panel.appendChild(el("p", "none")).className = "muted";
+			return;
+		}
+		var ul = el("ul");
+		items.forEach(function (item) { ul.appendChild(el("li", item)); });
+		panel.appendChild(ul);
+	}
+	function button(text, onclick) {
+		var b = el("button", text);
+		b.onclick = onclick;
+		panel.appendChild(b);
+		return b;
+	}
+	function describe(t) {
+		var text = t.label ? t.label + " " : "";
+		text += "-> " + t.to;
+		if (t.guard) { text += " [" + t.guard + "]"; }
+		if (t.actions) { text += " / " + t.actions.join(", "); }
+		return text;
+	}
+	function mark(selector, className, on) {
+		document.querySelectorAll(selector).forEach(function (e) {
+			e.classList.toggle(className, on(e));
+		});
+	}
+
+	function showState(name) {
+		var state = stateOf(name);
+		mark(".node", "selected", function (e) { return e.getAttribute("data-state") === name; });
+		panel.innerHTML = "";
+		panel.appendChild(el("h2", state.label || state.name));
+		if (state.label) { panel.appendChild(el("p", state.name)).className = "muted"; }
+		if (state.description) { panel.appendChild(el("p", state.description)); }
+		if (state.name === definition.initial) { panel.appendChild(el("p", "initial state")); }
+		if (state.final) { panel.appendChild(el("p", "final state")); }
+		list("Tags", state.tags);
+		list("Enter hooks", state.enterHooks);
+		list("Exit hooks", state.exitHooks);
+		list("Transitions", outgoing(name).map(describe));
+		panel.appendChild(el("h3", "Simulate"));
+		button("Start here", function () { history = [name]; simulate(); });
+	}
+
+	function simulate() {
+		var current = history[history.length - 1];
+		mark(".node", "current", function (e) { return e.getAttribute("data-state") === current; });
+		var taken = {};
+		for (var i = 1; i < history.length; i++) { taken[history[i - 1] + "->" + history[i]] = true; }
+		mark(".edge", "taken", function (e) { return taken[e.getAttribute("data-transition")]; });
+
+		panel.innerHTML = "";
+		panel.appendChild(el("h2", "Simulating"));
+		panel.appendChild(el("p", history.join(" -> ")));
+		panel.appendChild(el("h3", "Transit to"));
+		var transitions = outgoing(current);
+		if (transitions.length === 0) {
+			panel.appendChild(el("p", "no transition from " + current)).className = "muted";
+		}
+		transitions.forEach(function (t) {
+			button(describe(t), function () { history.push(t.to); simulate(); });
+		});
+		if (transitions.some(function (t) { return t.guard; })) {
+			panel.appendChild(el("p", "guards are assumed to pass")).className = "muted";
+		}
+		panel.appendChild(el("h3", "History"));
+		button("Back", function () { history.pop(); simulate(); }).disabled = history.length < 2;
+		button("Stop", function () {
+			history = [];
+			mark(".node", "current", function () { return false; });
+			mark(".edge", "taken", function () { return false; });
+			showState(current);
+		});
+	}
+
+	if (definition.initial) {
+		showState(definition.initial);
+	}
+
+	document.querySelectorAll(".node").forEach(function (node) {
+		node.addEventListener("click", function () {
+			var name = node.getAttribute("data-state");
+			if (history.length > 0) {
+				history = [name];
+				simulate();
+			} else {
+				showState(name);
+			}
+		});
+	});
+})();
+</script>
+</body>
+</html>
+`))
@@ -0,0 +1,49 @@
+package fsmviz
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var definitionLine = regexp.MustCompile(`var definition = (.*);\n`)
+
+func TestRenderHTML(t *testing.T) {
+	g := orderGraph()
+	var buf bytes.Buffer
+	if err := RenderHTML(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	page := buf.String()
+
+	match := definitionLine.FindStringSubmatch(page)
+	if match == nil {
+		t.Fatal("page embeds no definition")
+	}
+	var embedded Graph
+	if err := json.Unmarshal([]byte(match[1]), &embedded); err != nil {
+		t.Fatalf("embedded definition is not json: %v\n%s", err, match[1])
+	}
+	if !reflect.DeepEqual(&embedded, g) {
+		t.Errorf("embedded definition %+v\nwant %+v", embedded, *g)
+	}
+
+	// the viewer script finds nodes and edges by these attributes
+	for _, s := range g.States {
+		if want := `class="node" data-state="` + s.Name + `"`; !strings.Contains(page, want) {
+			t.Errorf("no svg node %s", want)
+		}
+	}
+	for _, tr := range g.Transitions {
+		if want := `class="edge" data-transition="` + tr.Key + `"`; !strings.Contains(page, strings.Replace(want, ">", "&gt;", -1)) {
+			t.Errorf("no svg edge %s", want)
+		}
+	}
+	// plus the arrow pointing at the initial state
+	if n := strings.Count(page, "data-transition="); n != len(g.Transitions)+1 {
+		t.Errorf("%d edges, want %d", n, len(g.Transitions)+1)
+	}
+}
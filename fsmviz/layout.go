@@ -0,0 +1,473 @@
+package fsmviz
+
+import (
+	"math"
+	"sort"
+)
+
+// layered layout in the style of Sugiyama:
+// break cycles, assign layers, insert dummy nodes for long edges,
+// reduce crossings with barycenter sweeps, then assign coordinates.
+
+const (
+	layerGap       = 70.0
+	nodeGap        = 30.0
+	dummyWidth     = 16.0
+	orderingSweeps = 12
+	placementSteps = 8
+)
+
+type point struct {
+	x, y float64
+}
+
+type layoutNode struct {
+	width, height float64
+	cluster       string
+	dummy         bool
+	layer         int
+	order         int
+	x, y          float64
+}
+
+type layoutEdge struct {
+	from, to int
+	// points from the source center to the target center, dummy nodes included
+	points []point
+}
+
+type layout struct {
+	nodes  []*layoutNode
+	edges  []*layoutEdge
+	layers [][]int
+	// clusters in order of first appearance
+	clusters []string
+	// chains are the vertices of each edge in layer order, dummy nodes included
+	chains   [][]int
+	reversed []bool
+	up       [][]int
+	down     [][]int
+	width    float64
+	height   float64
+}
+
+// newLayout lays out nodes of the given sizes, edges refer to node indexes.
+// When leftToRight is set layers run along the x axis instead of the y axis.
+func newLayout(sizes []point, clusters []string, edges [][2]int, leftToRight bool) *layout {
+	l := &layout{}
+	seen := make(map[string]bool)
+	for i, size := range sizes {
+		if c := clusters[i]; c != "" && !seen[c] {
+			seen[c] = true
+			l.clusters = append(l.clusters, c)
+		}
+		n := &layoutNode{width: size.x, height: size.y, cluster: clusters[i]}
+		if leftToRight {
+			n.width, n.height = size.y, size.x
+		}
+		l.nodes = append(l.nodes, n)
+	}
+	for _, e := range edges {
+		l.edges = append(l.edges, &layoutEdge{from: e[0], to: e[1]})
+	}
+
+	l.breakCycles()
+	l.assignLayers()
+	l.insertDummies()
+	l.orderLayers()
+	l.assignCoordinates()
+	l.routeEdges()
+	if leftToRight {
+		l.transpose()
+	}
+	return l
+}
+
+// breakCycles marks edges that go back to a node on the dfs stack,
+// reversing them makes the graph acyclic.
+func (l *layout) breakCycles() {
+	l.reversed = make([]bool, len(l.edges))
+	out := make([][]int, len(l.nodes))
+	for i, e := range l.edges {
+		if e.from != e.to {
+			out[e.from] = append(out[e.from], i)
+		}
+	}
+	const (
+		unvisited = iota
+		onStack
+		done
+	)
+	status := make([]int, len(l.nodes))
+	var visit func(v int)
+	visit = func(v int) {
+		status[v] = onStack
+		for _, i := range out[v] {
+			to := l.edges[i].to
+			switch status[to] {
+			case onStack:
+				l.reversed[i] = true
+			case unvisited:
+				visit(to)
+			}
+		}
+		status[v] = done
+	}
+	for v := range l.nodes {
+		if status[v] == unvisited {
+			visit(v)
+		}
+	}
+}
+
+// direction returns the edge endpoints with back edges reversed.
+func (l *layout) direction(i int) (from, to int) {
+	e := l.edges[i]
+	if l.reversed[i] {
+		return e.to, e.from
+	}
+	return e.from, e.to
+}
+
+// assignLayers puts every node one layer below its deepest predecessor.
+func (l *layout) assignLayers() {
+	preds := make([][]int, len(l.nodes))
+	for i := range l.edges {
+		from, to := l.direction(i)
+		if from != to {
+			preds[to] = append(preds[to], from)
+		}
+	}
+	assigned := make([]bool, len(l.nodes))
+	var layerOf func(v int) int
+	layerOf = func(v int) int {
+		if assigned[v] {
+			return l.nodes[v].layer
+		}
+		assigned[v] = true
+		layer := 0
+		for _, p := range preds[v] {
+			if pl := layerOf(p) + 1; pl > layer {
+				layer = pl
+			}
+		}
+		l.nodes[v].layer = layer
+		return layer
+	}
+	for v := range l.nodes {
+		layerOf(v)
+	}
+}
+
+// insertDummies splits edges spanning several layers into chains of dummy nodes.
+func (l *layout) insertDummies() {
+	l.chains = make([][]int, len(l.edges))
+	for i := range l.edges {
+		from, to := l.direction(i)
+		vertices := []int{from}
+		if from != to {
+			cluster := ""
+			if l.nodes[from].cluster == l.nodes[to].cluster {
+				cluster = l.nodes[from].cluster
+			}
+			for layer := l.nodes[from].layer + 1; layer < l.nodes[to].layer; layer++ {
+				l.nodes = append(l.nodes, &layoutNode{
+					width:   dummyWidth,
+					height:  dummyWidth,
+					cluster: cluster,
+					dummy:   true,
+					layer:   layer,
+				})
+				vertices = append(vertices, len(l.nodes)-1)
+			}
+			vertices = append(vertices, to)
+		}
+		l.chains[i] = vertices
+	}
+
+	depth := 0
+	for _, n := range l.nodes {
+		if n.layer+1 > depth {
+			depth = n.layer + 1
+		}
+	}
+	l.layers = make([][]int, depth)
+	for v, n := range l.nodes {
+		l.layers[n.layer] = append(l.layers[n.layer], v)
+	}
+	for _, vertices := range l.layers {
+		sort.SliceStable(vertices, func(i, j int) bool {
+			return l.clusterRank(vertices[i]) < l.clusterRank(vertices[j])
+		})
+		for i, v := range vertices {
+			l.nodes[v].order = i
+		}
+	}
+
+	l.up = make([][]int, len(l.nodes))
+	l.down = make([][]int, len(l.nodes))
+	for _, vertices := range l.chains {
+		for i := 1; i < len(vertices); i++ {
+			a, b := vertices[i-1], vertices[i]
+			l.down[a] = append(l.down[a], b)
+			l.up[b] = append(l.up[b], a)
+		}
+	}
+}
+
+// orderLayers reduces edge crossings by sorting each layer on the barycenter
+// of its neighbours, sweeping down and up and keeping the best ordering found.
+func (l *layout) orderLayers() {
+	best := l.snapshotOrder()
+	bestCrossings := l.crossings()
+	for sweep := 0; sweep < orderingSweeps && bestCrossings > 0; sweep++ {
+		if sweep%2 == 0 {
+			for layer := 1; layer < len(l.layers); layer++ {
+				l.sortLayer(layer, l.up)
+			}
+		} else {
+			for layer := len(l.layers) - 2; layer >= 0; layer-- {
+				l.sortLayer(layer, l.down)
+			}
+		}
+		if c := l.crossings(); c < bestCrossings {
+			bestCrossings = c
+			best = l.snapshotOrder()
+		}
+	}
+	l.restoreOrder(best)
+}
+
+// sortLayer orders a layer by the barycenter of neighbours in the adjacent layer.
+// Nodes of a cluster are kept next to each other at the end of the layer,
+// so that the box around a cluster spanning layers does not enclose other nodes.
+func (l *layout) sortLayer(layer int, neighbours [][]int) {
+	vertices := l.layers[layer]
+	barycenter := make(map[int]float64, len(vertices))
+	for _, v := range vertices {
+		if len(neighbours[v]) == 0 {
+			barycenter[v] = float64(l.nodes[v].order)
+			continue
+		}
+		sum := 0.0
+		for _, n := range neighbours[v] {
+			sum += float64(l.nodes[n].order)
+		}
+		barycenter[v] = sum / float64(len(neighbours[v]))
+	}
+
+	sort.SliceStable(vertices, func(i, j int) bool {
+		a, b := vertices[i], vertices[j]
+		if ca, cb := l.clusterRank(a), l.clusterRank(b); ca != cb {
+			return ca < cb
+		}
+		return barycenter[a] < barycenter[b]
+	})
+	for i, v := range vertices {
+		l.nodes[v].order = i
+	}
+}
+
+// clusterRank is 0 for nodes outside clusters, otherwise 1 + the cluster index.
+func (l *layout) clusterRank(v int) int {
+	for i, c := range l.clusters {
+		if l.nodes[v].cluster == c {
+			return i + 1
+		}
+	}
+	return 0
+}
+
+func (l *layout) crossings() int {
+	count := 0
+	for layer := 0; layer+1 < len(l.layers); layer++ {
+		var segments [][2]int
+		for _, v := range l.layers[layer] {
+			for _, n := range l.down[v] {
+				segments = append(segments, [2]int{l.nodes[v].order, l.nodes[n].order})
+			}
+		}
+		for i := range segments {
+			for j := i + 1; j < len(segments); j++ {
+				a, b := segments[i], segments[j]
+				if (a[0] < b[0] && a[1] > b[1]) || (a[0] > b[0] && a[1] < b[1]) {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+func (l *layout) snapshotOrder() [][]int {
+	snapshot := make([][]int, len(l.layers))
+	for i, vertices := range l.layers {
+		snapshot[i] = append([]int(nil), vertices...)
+	}
+	return snapshot
+}
+
+func (l *layout) restoreOrder(snapshot [][]int) {
+	l.layers = snapshot
+	for _, vertices := range l.layers {
+		for i, v := range vertices {
+			l.nodes[v].order = i
+		}
+	}
+}
+
+// assignCoordinates stacks layers vertically, then moves each node towards
+// the mean position of its neighbours while keeping the layer order and gaps.
+func (l *layout) assignCoordinates() {
+	y := 0.0
+	for _, vertices := range l.layers {
+		height := 0.0
+		for _, v := range vertices {
+			height = math.Max(height, l.nodes[v].height)
+		}
+		x := 0.0
+		for _, v := range vertices {
+			n := l.nodes[v]
+			n.x = x + n.width/2
+			n.y = y + height/2
+			x += n.width + l.gapAfter(v)
+		}
+		y += height + layerGap
+	}
+
+	for step := 0; step < placementSteps; step++ {
+		neighbours := l.up
+		if step%2 == 1 {
+			neighbours = l.down
+		}
+		for _, vertices := range l.layers {
+			for _, v := range vertices {
+				if len(neighbours[v]) == 0 {
+					continue
+				}
+				sum := 0.0
+				for _, n := range neighbours[v] {
+					sum += l.nodes[n].x
+				}
+				l.nodes[v].x = sum / float64(len(neighbours[v]))
+			}
+			l.separate(vertices)
+		}
+	}
+	l.separateClusters()
+	if len(l.nodes) == 0 {
+		// an empty graph has no extent
+		return
+	}
+
+	minX, maxX, maxY := math.Inf(1), math.Inf(-1), 0.0
+	for _, n := range l.nodes {
+		minX = math.Min(minX, n.x-n.width/2)
+		maxX = math.Max(maxX, n.x+n.width/2)
+		maxY = math.Max(maxY, n.y+n.height/2)
+	}
+	for _, n := range l.nodes {
+		n.x -= minX
+	}
+	l.width = maxX - minX
+	l.height = maxY
+}
+
+// gapAfter is the space to the next node in the layer, wider between clusters.
+func (l *layout) gapAfter(v int) float64 {
+	n := l.nodes[v]
+	if n.order+1 >= len(l.layers[n.layer]) {
+		return 0
+	}
+	next := l.nodes[l.layers[n.layer][n.order+1]]
+	if n.cluster != next.cluster {
+		return nodeGap * 1.5
+	}
+	return nodeGap
+}
+
+// separate pushes overlapping nodes of a layer apart around their mean position.
+func (l *layout) separate(vertices []int) {
+	if len(vertices) == 0 {
+		return
+	}
+	before := 0.0
+	for _, v := range vertices {
+		before += l.nodes[v].x
+	}
+	for i := 1; i < len(vertices); i++ {
+		prev, n := l.nodes[vertices[i-1]], l.nodes[vertices[i]]
+		min := prev.x + prev.width/2 + l.gapAfter(vertices[i-1]) + n.width/2
+		if n.x < min {
+			n.x = min
+		}
+	}
+	after := 0.0
+	for _, v := range vertices {
+		after += l.nodes[v].x
+	}
+	shift := (before - after) / float64(len(vertices))
+	for _, v := range vertices {
+		l.nodes[v].x += shift
+	}
+}
+
+// separateClusters shifts each cluster right until no other node
+// of the layers it spans lies within its horizontal extent.
+func (l *layout) separateClusters() {
+	for i, cluster := range l.clusters {
+		rank := i + 1
+		minLayer, maxLayer := len(l.layers), -1
+		left := math.Inf(1)
+		for _, n := range l.nodes {
+			if n.cluster == cluster {
+				minLayer = int(math.Min(float64(minLayer), float64(n.layer)))
+				maxLayer = int(math.Max(float64(maxLayer), float64(n.layer)))
+				left = math.Min(left, n.x-n.width/2)
+			}
+		}
+		shift := 0.0
+		for layer := minLayer; layer <= maxLayer; layer++ {
+			for _, v := range l.layers[layer] {
+				if n := l.nodes[v]; l.clusterRank(v) < rank {
+					shift = math.Max(shift, n.x+n.width/2+nodeGap*1.5-left)
+				}
+			}
+		}
+		for _, n := range l.nodes {
+			if n.cluster == cluster {
+				n.x += shift
+			}
+		}
+	}
+}
+
+// routeEdges builds the points of each edge in its original direction.
+func (l *layout) routeEdges() {
+	for i, e := range l.edges {
+		vertices := l.chains[i]
+		points := make([]point, 0, len(vertices))
+		for _, v := range vertices {
+			points = append(points, point{l.nodes[v].x, l.nodes[v].y})
+		}
+		if l.reversed[i] {
+			for a, b := 0, len(points)-1; a < b; a, b = a+1, b-1 {
+				points[a], points[b] = points[b], points[a]
+			}
+		}
+		e.points = points
+	}
+}
+
+func (l *layout) transpose() {
+	for _, n := range l.nodes {
+		n.x, n.y = n.y, n.x
+		n.width, n.height = n.height, n.width
+	}
+	for _, e := range l.edges {
+		for i, p := range e.points {
+			e.points[i] = point{p.y, p.x}
+		}
+	}
+	l.width, l.height = l.height, l.width
+}
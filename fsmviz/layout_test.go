@@ -0,0 +1,83 @@
+package fsmviz
+
+import (
+	"math"
+	"testing"
+)
+
+func sizes(n int) ([]point, []string) {
+	s := make([]point, n)
+	for i := range s {
+		s[i] = point{60, nodeHeight}
+	}
+	return s, make([]string, n)
+}
+
+func TestLayoutLayersFollowEdges(t *testing.T) {
+	s, clusters := sizes(4)
+	edges := [][2]int{{0, 1}, {1, 2}, {2, 3}, {0, 2}, {3, 0}}
+	l := newLayout(s, clusters, edges, false)
+	for i, e := range l.edges {
+		from, to := l.nodes[e.from], l.nodes[e.to]
+		if l.reversed[i] {
+			from, to = to, from
+		}
+		if from.layer >= to.layer {
+			t.Errorf("edge %d->%d goes from layer %d to %d", e.from, e.to, from.layer, to.layer)
+		}
+		if from.y >= to.y {
+			t.Errorf("edge %d->%d goes up from y %.1f to %.1f", e.from, e.to, from.y, to.y)
+		}
+	}
+	if !l.reversed[4] {
+		t.Errorf("back edge 3->0 is not reversed")
+	}
+	// 0->2 spans two layers, it needs a dummy node
+	if got := len(l.chains[3]); got != 3 {
+		t.Errorf("chain of 0->2 has %d vertices, want 3", got)
+	}
+}
+
+func TestLayoutNoOverlap(t *testing.T) {
+	s, clusters := sizes(6)
+	edges := [][2]int{{0, 1}, {0, 2}, {0, 3}, {0, 4}, {1, 5}, {4, 5}}
+	l := newLayout(s, clusters, edges, false)
+	for layer, vertices := range l.layers {
+		for i := 1; i < len(vertices); i++ {
+			prev, n := l.nodes[vertices[i-1]], l.nodes[vertices[i]]
+			if gap := (n.x - n.width/2) - (prev.x + prev.width/2); gap < nodeGap-1e-9 {
+				t.Errorf("layer %d: nodes %d and %d are %.1f apart", layer, vertices[i-1], vertices[i], gap)
+			}
+		}
+	}
+	for i, n := range l.nodes {
+		if n.x-n.width/2 < -1e-9 || n.x+n.width/2 > l.width+1e-9 {
+			t.Errorf("node %d at x %.1f is outside width %.1f", i, n.x, l.width)
+		}
+	}
+}
+
+func TestLayoutClustersDoNotInterleave(t *testing.T) {
+	s, _ := sizes(4)
+	clusters := []string{"a", "b", "a", "b"}
+	l := newLayout(s, clusters, [][2]int{{0, 1}, {0, 2}, {0, 3}}, false)
+	// 1, 2 and 3 share a layer, cluster a comes first
+	if !(l.nodes[2].x < l.nodes[1].x && l.nodes[2].x < l.nodes[3].x) {
+		t.Errorf("cluster a is not left of cluster b: %.1f %.1f %.1f", l.nodes[1].x, l.nodes[2].x, l.nodes[3].x)
+	}
+}
+
+func TestLayoutLeftToRight(t *testing.T) {
+	s, clusters := sizes(2)
+	l := newLayout(s, clusters, [][2]int{{0, 1}}, true)
+	if !(l.nodes[0].x < l.nodes[1].x) || l.nodes[0].y != l.nodes[1].y {
+		t.Errorf("layers do not run along x: %+v %+v", *l.nodes[0], *l.nodes[1])
+	}
+}
+
+func TestLayoutEmpty(t *testing.T) {
+	l := newLayout(nil, nil, nil, false)
+	if l.width != 0 || l.height != 0 || math.IsInf(l.width, 0) {
+		t.Errorf("empty layout is %.1f x %.1f", l.width, l.height)
+	}
+}
@@ -0,0 +1,173 @@
+package fsmviz
+
+// Format is the output format of Render.
+type Format string
+
+const (
+	FormatDOT Format = "dot"
+	FormatSVG Format = "svg"
+	FormatPNG Format = "png"
+	FormatJPG Format = "jpg"
+)
+
+// RankDir is the direction the diagram is laid out in.
+type RankDir string
+
+const (
+	RankDirTB RankDir = "TB"
+	RankDirLR RankDir = "LR"
+)
+
+// Theme is a set of colors used to draw the diagram, empty colors are left to renderer defaults.
+type Theme struct {
+	Background   string
+	FontName     string
+	NodeColor    string
+	FontColor    string
+	EdgeColor    string
+	CurrentColor string
+	PathColor    string
+	DimColor     string
+}
+
+var (
+	ThemeDefault = Theme{
+		CurrentColor: "lightblue",
+		PathColor:    "red",
+		DimColor:     "gray",
+	}
+	ThemeDark = Theme{
+		Background:   "#1e1e1e",
+		NodeColor:    "#d4d4d4",
+		FontColor:    "#d4d4d4",
+		EdgeColor:    "#d4d4d4",
+		CurrentColor: "#264f78",
+		PathColor:    "#f44747",
+		DimColor:     "#5a5a5a",
+	}
+	ThemeMonochrome = Theme{
+		NodeColor:    "black",
+		FontColor:    "black",
+		EdgeColor:    "black",
+		CurrentColor: "lightgray",
+		PathColor:    "black",
+		DimColor:     "gray",
+	}
+)
+
+// Options are the resolved render options, renderers read them via NewOptions.
+type Options struct {
+	Layout         string
+	Theme          Theme
+	RankDir        RankDir
+	ClusterTags    []string
+	CurrentState   string
+	Path           []string
+	DimUnreachable bool
+	ActiveFrom     string
+	ActiveTo       string
+	Caption        string
+	StateHeat      map[string]int
+	TransitionHeat map[string]int
+}
+
+// Option customizes the output of Render.
+type Option func(o *Options)
+
+func NewOptions(opts ...Option) *Options {
+	o := &Options{Theme: ThemeDefault}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// WithLayout sets the graphviz layout engine, such as "dot" (default), "circo" or "neato".
+// It is ignored by the pure go svg renderer.
+func WithLayout(layout string) Option {
+	return func(o *Options) {
+		o.Layout = layout
+	}
+}
+
+// WithTheme sets colors of the diagram, see ThemeDefault, ThemeDark and ThemeMonochrome.
+func WithTheme(theme Theme) Option {
+	return func(o *Options) {
+		o.Theme = theme
+	}
+}
+
+// WithRankDir lays out the diagram top to bottom (default) or left to right.
+func WithRankDir(dir RankDir) Option {
+	return func(o *Options) {
+		o.RankDir = dir
+	}
+}
+
+// WithClusterByTag groups states carrying one of the tags into a box labeled with the tag.
+// A state carrying several of the tags goes to the first one.
+func WithClusterByTag(tags ...string) Option {
+	return func(o *Options) {
+		o.ClusterTags = tags
+	}
+}
+
+// WithCurrentState fills the given state with color, e.g. WithCurrentState(order.GetCurrentStatus()).
+func WithCurrentState(state string) Option {
+	return func(o *Options) {
+		o.CurrentState = state
+	}
+}
+
+// WithPath highlights a history of visited states, e.g. created, paid, cancelled.
+// Edges taken are numbered in visiting order, a step that has no transition
+// (such as a forced SetState) is drawn as a dashed edge.
+func WithPath(states ...string) Option {
+	return func(o *Options) {
+		o.Path = states
+	}
+}
+
+// WithDimUnreachable grays out states that can not be reached from the current state.
+// It has no effect without WithCurrentState.
+func WithDimUnreachable() Option {
+	return func(o *Options) {
+		o.DimUnreachable = true
+	}
+}
+
+// WithActiveTransition highlights the transition just taken, without numbering.
+// A step that has no transition is drawn as a dashed edge.
+func WithActiveTransition(from, to string) Option {
+	return func(o *Options) {
+		o.ActiveFrom = from
+		o.ActiveTo = to
+	}
+}
+
+// WithCaption writes a line of text under the diagram.
+func WithCaption(caption string) Option {
+	return func(o *Options) {
+		o.Caption = caption
+	}
+}
+
+// WithHeat colors states and transitions by hit count, from pale to dark red,
+// and appends the count to their labels. Transitions are keyed by fsm.GenTransitionKey,
+// states and transitions without hits are dimmed and dashed.
+func WithHeat(states, transitions map[string]int) Option {
+	return func(o *Options) {
+		o.StateHeat = states
+		o.TransitionHeat = transitions
+	}
+}
+
+// ClusterTag returns the first of the cluster tags carried by state.
+func (o *Options) ClusterTag(state *State) string {
+	for _, tag := range o.ClusterTags {
+		if state.HasTag(tag) {
+			return tag
+		}
+	}
+	return ""
+}
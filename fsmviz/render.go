@@ -0,0 +1,27 @@
+package fsmviz
+
+import (
+	"fmt"
+	"io"
+)
+
+// Render writes the diagram of g to w as dot or svg without any cgo dependency.
+// Use the fsmviz/graphviz subpackage for png and jpg, or for graphviz layouts.
+func Render(w io.Writer, g *Graph, format Format, opts ...Option) error {
+	sg, err := newStyledGraph(g, NewOptions(opts...))
+	if err != nil {
+		return err
+	}
+	switch format {
+	case FormatDOT:
+		return writeDOT(w, sg)
+	case FormatSVG:
+		return writeSVG(w, sg)
+	}
+	return fmt.Errorf("[fsm] unsupported render format %q, use fsmviz/graphviz", format)
+}
+
+// WriteDOT writes the diagram of g in graphviz dot language, without layout.
+func WriteDOT(w io.Writer, g *Graph, opts ...Option) error {
+	return Render(w, g, FormatDOT, opts...)
+}
@@ -0,0 +1,22 @@
+package fsmviz
+
+import "time"
+
+// Step is a recorded transition of an instance, used to replay its history.
+type Step struct {
+	From string
+	To   string
+	At   time.Time
+}
+
+// PathOf returns the visited states of steps, for WithPath.
+func PathOf(steps []Step) []string {
+	if len(steps) == 0 {
+		return nil
+	}
+	path := []string{steps[0].From}
+	for _, step := range steps {
+		path = append(path, step.To)
+	}
+	return path
+}
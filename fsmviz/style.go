@@ -0,0 +1,234 @@
+package fsmviz
+
+import (
+	"fmt"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"strings"
+)
+
+// styledGraph is a Graph with options applied, shared by the dot and svg renderers.
+type styledGraph struct {
+	name     string
+	theme    Theme
+	rankDir  RankDir
+	caption  string
+	nodes    []styledNode
+	edges    []styledEdge
+	clusters []string
+}
+
+type styledNode struct {
+	name      string
+	label     string
+	tooltip   string
+	shape     string
+	color     string
+	fontColor string
+	fillColor string
+	cluster   string
+}
+
+type styledEdge struct {
+	from      string
+	to        string
+	key       string
+	label     string
+	tooltip   string
+	color     string
+	fontColor string
+	dashed    bool
+	penWidth  float64
+}
+
+func newStyledGraph(g *Graph, o *Options) (*styledGraph, error) {
+	steps, err := g.PathSteps(o.Path)
+	if err != nil {
+		return nil, err
+	}
+	var reachable map[string]bool
+	if o.DimUnreachable && o.CurrentState != "" {
+		reachable = g.Reachable(o.CurrentState)
+	}
+	theme := o.Theme
+	sg := &styledGraph{name: g.Name, theme: theme, rankDir: o.RankDir, caption: o.Caption}
+	activeKey := ""
+	if o.ActiveFrom != "" || o.ActiveTo != "" {
+		if !g.hasState(o.ActiveFrom) || !g.hasState(o.ActiveTo) {
+			return nil, fmt.Errorf("[fsm] transition not defined from %s to %s", o.ActiveFrom, o.ActiveTo)
+		}
+		activeKey = fsm.GenTransitionKey(o.ActiveFrom, o.ActiveTo)
+	}
+	drawn := make(map[string]bool)
+
+	seenClusters := make(map[string]bool)
+	for i := range g.States {
+		state := &g.States[i]
+		node := styledNode{
+			name:      state.Name,
+			label:     state.DisplayLabel(),
+			tooltip:   state.Description,
+			shape:     state.Shape,
+			color:     theme.NodeColor,
+			fontColor: theme.FontColor,
+			fillColor: state.Color,
+			cluster:   o.ClusterTag(state),
+		}
+		if node.shape == "" && (state.Final || state.HasTag(TagTerminal)) {
+			node.shape = "doublecircle"
+		}
+		if reachable != nil && !reachable[state.Name] {
+			node.color = theme.DimColor
+			node.fontColor = theme.DimColor
+		}
+		if o.StateHeat != nil {
+			hits := o.StateHeat[state.Name]
+			node.label = fmt.Sprintf("%s (%d)", node.label, hits)
+			if hits == 0 {
+				node.color = theme.DimColor
+				node.fontColor = theme.DimColor
+			} else {
+				level := heatLevel(hits, maxHeat(o.StateHeat))
+				node.fillColor = heatColors[level]
+				if level >= 3 {
+					node.fontColor = "white"
+				}
+			}
+		}
+		if state.Name == o.CurrentState {
+			node.fillColor = theme.CurrentColor
+		}
+		if node.cluster != "" && !seenClusters[node.cluster] {
+			seenClusters[node.cluster] = true
+			sg.clusters = append(sg.clusters, node.cluster)
+		}
+		sg.nodes = append(sg.nodes, node)
+	}
+
+	if g.Initial != "" {
+		startColor := theme.NodeColor
+		if startColor == "" {
+			startColor = "black"
+		}
+		sg.nodes = append(sg.nodes, styledNode{
+			name:      startNode,
+			shape:     "point",
+			color:     theme.NodeColor,
+			fillColor: startColor,
+		})
+		sg.edges = append(sg.edges, styledEdge{
+			from:  startNode,
+			to:    g.Initial,
+			key:   startNode + "->" + g.Initial,
+			color: theme.EdgeColor,
+		})
+	}
+
+	for i := range g.Transitions {
+		transition := &g.Transitions[i]
+		edge := styledEdge{
+			from:      transition.From,
+			to:        transition.To,
+			key:       transition.Key,
+			label:     transition.DisplayLabel(),
+			tooltip:   transition.Description,
+			color:     theme.EdgeColor,
+			fontColor: theme.FontColor,
+		}
+		if transition.Color != "" {
+			edge.color = transition.Color
+		}
+		if reachable != nil && !reachable[transition.From] {
+			edge.color = theme.DimColor
+			edge.fontColor = theme.DimColor
+		}
+		if o.TransitionHeat != nil {
+			hits := o.TransitionHeat[transition.Key]
+			edge.label = strings.TrimSpace(fmt.Sprintf("%s (%d)", edge.label, hits))
+			if hits == 0 {
+				edge.color = theme.DimColor
+				edge.fontColor = theme.DimColor
+				edge.dashed = true
+			} else {
+				edge.color = heatColors[heatLevel(hits, maxHeat(o.TransitionHeat))]
+				edge.fontColor = edge.color
+				edge.penWidth = 1 + 2*float64(hits)/float64(maxHeat(o.TransitionHeat))
+			}
+		}
+		if transition.Key == activeKey {
+			edge.color = theme.PathColor
+			edge.fontColor = theme.PathColor
+			edge.penWidth = 2
+		}
+		if numbers, ok := steps[transition.Key]; ok {
+			edge.label = joinStepLabel(numbers, edge.label)
+			edge.color = theme.PathColor
+			edge.fontColor = theme.PathColor
+			edge.penWidth = 2
+		}
+		drawn[transition.Key] = true
+		sg.edges = append(sg.edges, edge)
+	}
+
+	for _, forced := range g.ForcedSteps(o.Path) {
+		drawn[forced.Key] = true
+		sg.edges = append(sg.edges, styledEdge{
+			from:      forced.From,
+			to:        forced.To,
+			key:       forced.Key + "#forced",
+			label:     joinStepLabel(steps[forced.Key], ""),
+			color:     theme.PathColor,
+			fontColor: theme.PathColor,
+			dashed:    true,
+		})
+	}
+	if activeKey != "" && !drawn[activeKey] {
+		sg.edges = append(sg.edges, styledEdge{
+			from:      o.ActiveFrom,
+			to:        o.ActiveTo,
+			key:       activeKey + "#forced",
+			color:     theme.PathColor,
+			fontColor: theme.PathColor,
+			dashed:    true,
+		})
+	}
+	return sg, nil
+}
+
+// heatColors goes from few to many hits.
+var heatColors = []string{"#fee5d9", "#fcae91", "#fb6a4a", "#de2d26", "#a50f15"}
+
+// heatLevel is the index in heatColors for hits out of max.
+func heatLevel(hits, max int) int {
+	level := (hits*len(heatColors) - 1) / max
+	if level >= len(heatColors) {
+		level = len(heatColors) - 1
+	}
+	return level
+}
+
+func maxHeat(heat map[string]int) int {
+	max := 1
+	for _, hits := range heat {
+		if hits > max {
+			max = hits
+		}
+	}
+	return max
+}
+
+// startNode points to the initial state.
+const startNode = "__start"
+
+func joinStepLabel(numbers []string, label string) string {
+	steps := ""
+	for i, n := range numbers {
+		if i > 0 {
+			steps += ","
+		}
+		steps += n
+	}
+	if label == "" {
+		return steps
+	}
+	return steps + " " + label
+}
@@ -0,0 +1,344 @@
+package fsmviz
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"unicode/utf8"
+)
+
+const (
+	fontSize      = 14.0
+	charWidth     = 7.5
+	nodeHeight    = 36.0
+	nodePadding   = 24.0
+	minNodeWidth  = 54.0
+	margin        = 20.0
+	clusterMargin = 14.0
+	arrowLength   = 10.0
+	pointSize     = 10.0
+	defaultFont   = "Helvetica, Arial, sans-serif"
+)
+
+// svgDiagram is a styled graph with its layout, in svg coordinates.
+type svgDiagram struct {
+	sg     *styledGraph
+	layout *layout
+}
+
+func newSVGDiagram(sg *styledGraph) *svgDiagram {
+	index := make(map[string]int, len(sg.nodes))
+	sizes := make([]point, len(sg.nodes))
+	clusters := make([]string, len(sg.nodes))
+	for i, node := range sg.nodes {
+		index[node.name] = i
+		sizes[i] = nodeSize(node)
+		clusters[i] = node.cluster
+	}
+	edges := make([][2]int, len(sg.edges))
+	for i, edge := range sg.edges {
+		edges[i] = [2]int{index[edge.from], index[edge.to]}
+	}
+	l := newLayout(sizes, clusters, edges, sg.rankDir == RankDirLR)
+
+	// leave room for cluster boxes and the self loops on the right
+	offsetX, offsetY := margin, margin
+	if len(sg.clusters) > 0 {
+		offsetX += clusterMargin
+		offsetY += clusterMargin + fontSize
+	}
+	for _, n := range l.nodes {
+		n.x += offsetX
+		n.y += offsetY
+	}
+	for _, e := range l.edges {
+		for i := range e.points {
+			e.points[i].x += offsetX
+			e.points[i].y += offsetY
+		}
+	}
+	l.width += 2*offsetX + nodeHeight
+	l.height += offsetY + margin + clusterMargin
+	if sg.caption != "" {
+		l.width = math.Max(l.width, textWidth(sg.caption)+2*margin)
+		l.height += fontSize + margin
+	}
+	return &svgDiagram{sg: sg, layout: l}
+}
+
+func nodeSize(node styledNode) point {
+	width := math.Max(textWidth(node.label)+nodePadding, minNodeWidth)
+	switch node.shape {
+	case "circle":
+		return point{width, width}
+	case "doublecircle":
+		return point{width + 8, width + 8}
+	case "point":
+		return point{pointSize, pointSize}
+	}
+	return point{width, nodeHeight}
+}
+
+func textWidth(s string) float64 {
+	return float64(utf8.RuneCountInString(s)) * charWidth
+}
+
+// writeSVG lays out and writes the graph as a standalone svg document.
+func writeSVG(w io.Writer, sg *styledGraph) error {
+	d := newSVGDiagram(sg)
+	bw := bufio.NewWriter(w)
+	d.write(bw)
+	return bw.Flush()
+}
+
+func (d *svgDiagram) write(w io.Writer) {
+	theme := d.sg.theme
+	font := defaultFont
+	if theme.FontName != "" {
+		font = theme.FontName
+	}
+	fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f" font-family="%s" font-size="%.0f">`+"\n",
+		d.layout.width, d.layout.height, d.layout.width, d.layout.height, escape(font), fontSize)
+	fmt.Fprintf(w, "<title>%s</title>\n", escape(d.sg.name))
+	if theme.Background != "" {
+		fmt.Fprintf(w, `<rect width="100%%" height="100%%" fill="%s"/>`+"\n", escape(theme.Background))
+	}
+	for _, cluster := range d.sg.clusters {
+		d.writeCluster(w, cluster)
+	}
+	for i := range d.sg.edges {
+		d.writeEdge(w, i)
+	}
+	for i := range d.sg.nodes {
+		d.writeNode(w, i)
+	}
+	if d.sg.caption != "" {
+		fmt.Fprintf(w, `<text class="caption" x="%.1f" y="%.1f" text-anchor="middle" fill="%s">%s</text>`+"\n",
+			d.layout.width/2, d.layout.height-margin, colorOr(theme.FontColor, "black"), escape(d.sg.caption))
+	}
+	fmt.Fprint(w, "</svg>\n")
+}
+
+func (d *svgDiagram) writeCluster(w io.Writer, cluster string) {
+	minX, minY := math.Inf(1), math.Inf(1)
+	maxX, maxY := math.Inf(-1), math.Inf(-1)
+	for _, n := range d.layout.nodes {
+		if n.cluster != cluster {
+			continue
+		}
+		minX = math.Min(minX, n.x-n.width/2)
+		minY = math.Min(minY, n.y-n.height/2)
+		maxX = math.Max(maxX, n.x+n.width/2)
+		maxY = math.Max(maxY, n.y+n.height/2)
+	}
+	minX -= clusterMargin
+	maxX += clusterMargin
+	minY -= clusterMargin + fontSize
+	maxY += clusterMargin
+	theme := d.sg.theme
+	fmt.Fprintf(w, `<g class="cluster" data-cluster="%s">`, escape(cluster))
+	fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" rx="4" fill="none" stroke="%s"/>`,
+		minX, minY, maxX-minX, maxY-minY, colorOr(theme.NodeColor, "black"))
+	fmt.Fprintf(w, `<text x="%.1f" y="%.1f" text-anchor="middle" fill="%s">%s</text>`,
+		(minX+maxX)/2, minY+fontSize+2, colorOr(theme.FontColor, "black"), escape(cluster))
+	fmt.Fprint(w, "</g>\n")
+}
+
+func (d *svgDiagram) writeNode(w io.Writer, i int) {
+	node := d.sg.nodes[i]
+	n := d.layout.nodes[i]
+	stroke := colorOr(node.color, "black")
+	fill := colorOr(node.fillColor, "none")
+	if node.shape == "point" {
+		fmt.Fprintf(w, `<g class="start"><circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s" stroke="%s"/></g>`+"\n",
+			n.x, n.y, n.width/2, fill, stroke)
+		return
+	}
+	fmt.Fprintf(w, `<g class="node" data-state="%s">`, escape(node.name))
+	if node.tooltip != "" {
+		fmt.Fprintf(w, "<title>%s</title>", escape(node.tooltip))
+	}
+	switch node.shape {
+	case "box", "rect", "rectangle", "square":
+		fmt.Fprintf(w, `<rect x="%.1f" y="%.1f" width="%.1f" height="%.1f" fill="%s" stroke="%s"/>`,
+			n.x-n.width/2, n.y-n.height/2, n.width, n.height, fill, stroke)
+	case "circle":
+		fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s" stroke="%s"/>`,
+			n.x, n.y, n.width/2, fill, stroke)
+	case "doublecircle":
+		fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="%s" stroke="%s"/>`,
+			n.x, n.y, n.width/2, fill, stroke)
+		fmt.Fprintf(w, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="none" stroke="%s"/>`,
+			n.x, n.y, n.width/2-4, stroke)
+	default:
+		fmt.Fprintf(w, `<ellipse cx="%.1f" cy="%.1f" rx="%.1f" ry="%.1f" fill="%s" stroke="%s"/>`,
+			n.x, n.y, n.width/2, n.height/2, fill, stroke)
+	}
+	fmt.Fprintf(w, `<text x="%.1f" y="%.1f" text-anchor="middle" dominant-baseline="central" fill="%s">%s</text>`,
+		n.x, n.y, colorOr(node.fontColor, "black"), escape(node.label))
+	fmt.Fprint(w, "</g>\n")
+}
+
+func (d *svgDiagram) writeEdge(w io.Writer, i int) {
+	edge := d.sg.edges[i]
+	from := d.layout.nodes[d.layout.edges[i].from]
+	to := d.layout.nodes[d.layout.edges[i].to]
+	stroke := colorOr(edge.color, "black")
+	width := edge.penWidth
+	if width == 0 {
+		width = 1
+	}
+
+	var path string
+	var tip, tail, labelAt point
+	if from == to {
+		path, tail, tip, labelAt = selfLoop(from)
+	} else {
+		points := append([]point(nil), d.layout.edges[i].points...)
+		if d.hasOpposite(i) {
+			points = bend(points, 10)
+		}
+		points[0] = clip(d.sg.nodes[d.layout.edges[i].from].shape, from, points[1])
+		last := len(points) - 1
+		points[last] = clip(d.sg.nodes[d.layout.edges[i].to].shape, to, points[last-1])
+		tail = points[last-1]
+		tip = points[last]
+		points[last] = shorten(tail, tip, arrowLength)
+		path = smoothPath(points)
+		labelAt = midpoint(points)
+	}
+
+	fmt.Fprintf(w, `<g class="edge" data-transition="%s" data-from="%s" data-to="%s">`,
+		escape(edge.key), escape(edge.from), escape(edge.to))
+	title := edge.key
+	if edge.tooltip != "" {
+		title = edge.tooltip
+	}
+	fmt.Fprintf(w, "<title>%s</title>", escape(title))
+	dash := ""
+	if edge.dashed {
+		dash = ` stroke-dasharray="5,3"`
+	}
+	fmt.Fprintf(w, `<path d="%s" fill="none" stroke="%s" stroke-width="%g"%s/>`, path, stroke, width, dash)
+	fmt.Fprintf(w, `<polygon points="%s" fill="%s" stroke="%s"/>`, arrowHead(tail, tip), stroke, stroke)
+	if edge.label != "" {
+		fmt.Fprintf(w, `<text x="%.1f" y="%.1f" dx="4" fill="%s">%s</text>`,
+			labelAt.x, labelAt.y, colorOr(edge.fontColor, "black"), escape(edge.label))
+	}
+	fmt.Fprint(w, "</g>\n")
+}
+
+// hasOpposite reports whether another edge links the same two nodes,
+// such edges are bent apart so they do not overlap.
+func (d *svgDiagram) hasOpposite(i int) bool {
+	e := d.layout.edges[i]
+	for j, other := range d.layout.edges {
+		if j != i && ((other.from == e.to && other.to == e.from) || (other.from == e.from && other.to == e.to)) {
+			return true
+		}
+	}
+	return false
+}
+
+// bend moves the inner points of an edge sideways, to the right of its direction.
+func bend(points []point, offset float64) []point {
+	if len(points) == 2 {
+		mid := point{(points[0].x + points[1].x) / 2, (points[0].y + points[1].y) / 2}
+		points = []point{points[0], mid, points[1]}
+	}
+	dx, dy := points[len(points)-1].x-points[0].x, points[len(points)-1].y-points[0].y
+	length := math.Hypot(dx, dy)
+	if length == 0 {
+		return points
+	}
+	nx, ny := -dy/length*offset, dx/length*offset
+	for i := 1; i < len(points)-1; i++ {
+		points[i].x += nx
+		points[i].y += ny
+	}
+	return points
+}
+
+// clip moves from the center of a node to its border in the direction of p.
+func clip(shape string, n *layoutNode, p point) point {
+	dx, dy := p.x-n.x, p.y-n.y
+	if dx == 0 && dy == 0 {
+		return point{n.x, n.y}
+	}
+	rx, ry := n.width/2, n.height/2
+	var t float64
+	switch shape {
+	case "box", "rect", "rectangle", "square":
+		t = 1 / math.Max(math.Abs(dx)/rx, math.Abs(dy)/ry)
+	default:
+		t = 1 / math.Sqrt(dx*dx/(rx*rx)+dy*dy/(ry*ry))
+	}
+	return point{n.x + dx*t, n.y + dy*t}
+}
+
+// shorten moves the end of a segment back by length, making room for the arrow head.
+func shorten(from, to point, length float64) point {
+	dx, dy := to.x-from.x, to.y-from.y
+	d := math.Hypot(dx, dy)
+	if d <= length {
+		return from
+	}
+	return point{to.x - dx/d*length, to.y - dy/d*length}
+}
+
+func arrowHead(from, tip point) string {
+	dx, dy := tip.x-from.x, tip.y-from.y
+	d := math.Hypot(dx, dy)
+	if d == 0 {
+		dx, dy, d = 0, 1, 1
+	}
+	ux, uy := dx/d, dy/d
+	baseX, baseY := tip.x-ux*arrowLength, tip.y-uy*arrowLength
+	half := arrowLength / 2.5
+	return fmt.Sprintf("%.1f,%.1f %.1f,%.1f %.1f,%.1f",
+		tip.x, tip.y, baseX-uy*half, baseY+ux*half, baseX+uy*half, baseY-ux*half)
+}
+
+// smoothPath draws a polyline with its corners rounded by quadratic curves.
+func smoothPath(points []point) string {
+	path := fmt.Sprintf("M%.1f,%.1f", points[0].x, points[0].y)
+	for i := 1; i < len(points)-1; i++ {
+		next := point{(points[i].x + points[i+1].x) / 2, (points[i].y + points[i+1].y) / 2}
+		path += fmt.Sprintf(" Q%.1f,%.1f %.1f,%.1f", points[i].x, points[i].y, next.x, next.y)
+	}
+	last := points[len(points)-1]
+	return path + fmt.Sprintf(" L%.1f,%.1f", last.x, last.y)
+}
+
+func midpoint(points []point) point {
+	if len(points)%2 == 1 {
+		return points[len(points)/2]
+	}
+	a, b := points[len(points)/2-1], points[len(points)/2]
+	return point{(a.x + b.x) / 2, (a.y + b.y) / 2}
+}
+
+// selfLoop draws a loop on the right side of a node.
+func selfLoop(n *layoutNode) (path string, tail, tip, labelAt point) {
+	start := point{n.x + n.width/2*0.8, n.y - n.height/4}
+	tip = point{n.x + n.width/2*0.8, n.y + n.height/4}
+	reach := n.x + n.width/2 + nodeHeight*0.8
+	tail = point{reach, tip.y + 4}
+	end := shorten(tail, tip, arrowLength)
+	path = fmt.Sprintf("M%.1f,%.1f C%.1f,%.1f %.1f,%.1f %.1f,%.1f",
+		start.x, start.y, reach, n.y-n.height, reach, n.y+n.height, end.x, end.y)
+	return path, tail, tip, point{reach, n.y}
+}
+
+func colorOr(color, fallback string) string {
+	if color == "" {
+		return fallback
+	}
+	return escape(color)
+}
+
+func escape(s string) string {
+	return html.EscapeString(s)
+}
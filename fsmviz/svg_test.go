@@ -0,0 +1,126 @@
+package fsmviz
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"flag"
+	"github.com/FingerLiu/go-fsm/fsm"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+func isPhysical(ctx context.Context, state string) (bool, error) {
+	return true, nil
+}
+
+func orderGraph() *Graph {
+	f := fsm.NewFSM(context.Background(), "order").
+		AddStates("created", "cancelled", "paid", "checkout", "delivering", "delivered", "finished").
+		SetInitial("created").
+		AddTransition("created", "cancelled").
+		AddTransition("created", "paid").
+		AddTransition("paid", "checkout").
+		AddTransition("checkout", "delivering").
+		AddTransition("delivering", "delivered").
+		AddTransition("delivered", "finished").
+		AddTransitionOn("paid", "cancelled", isPhysical).
+		AddFinalStates("finished", "cancelled").
+		SetStateMeta("delivering", fsm.StateMeta{Tags: []string{"shipping"}}).
+		SetStateMeta("delivered", fsm.StateMeta{Tags: []string{"shipping"}}).
+		SetTransitionMeta("created", "paid", fsm.TransitionMeta{Label: "pay"})
+	return FromFSM(f)
+}
+
+func TestRenderSVGGolden(t *testing.T) {
+	tests := []struct {
+		name  string
+		graph *Graph
+		opts  []Option
+	}{
+		{"order", orderGraph(), nil},
+		{"order_lr_clusters", orderGraph(), []Option{WithRankDir(RankDirLR), WithClusterByTag("shipping")}},
+		{"order_path", orderGraph(), []Option{WithCurrentState("checkout"), WithPath("created", "paid", "checkout")}},
+		{"empty", &Graph{Name: "empty"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Render(&buf, tt.graph, FormatSVG, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			checkSVG(t, buf.Bytes())
+
+			golden := filepath.Join("testdata", tt.name+".svg")
+			if *update {
+				if err := os.WriteFile(golden, buf.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v, run go test -update", err)
+			}
+			if !bytes.Equal(buf.Bytes(), want) {
+				t.Errorf("svg differs from %s, run go test -update if the change is intended", golden)
+			}
+		})
+	}
+}
+
+func TestRenderSVGDeterministic(t *testing.T) {
+	var first, second bytes.Buffer
+	if err := Render(&first, orderGraph(), FormatSVG); err != nil {
+		t.Fatal(err)
+	}
+	if err := Render(&second, orderGraph(), FormatSVG); err != nil {
+		t.Fatal(err)
+	}
+	if first.String() != second.String() {
+		t.Error("two renders of the same graph differ")
+	}
+}
+
+// checkSVG fails on malformed xml or non finite numbers.
+func checkSVG(t *testing.T, svg []byte) {
+	t.Helper()
+	for _, bad := range []string{"Inf", "NaN"} {
+		if bytes.Contains(svg, []byte(bad)) {
+			t.Errorf("svg contains %s", bad)
+		}
+	}
+	d := xml.NewDecoder(bytes.NewReader(svg))
+	for {
+		_, err := d.Token()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			t.Fatalf("invalid svg: %v\n%s", err, svg)
+		}
+	}
+}
+
+func TestRenderDOT(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, orderGraph(), FormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+	for _, want := range []string{`"created" -> "paid"`, `label="pay"`, `label="[isPhysical]"`} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot has no %s:\n%s", want, dot)
+		}
+	}
+}
+
+func TestRenderUnsupportedFormat(t *testing.T) {
+	if err := Render(io.Discard, orderGraph(), FormatPNG); err == nil {
+		t.Error("png rendered without graphviz")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log"
+	"time"
 )
 
 type FSM struct {
@@ -13,6 +14,10 @@ type FSM struct {
 	transitions     []*Transition
 	globalEnterHook func(ctx context.Context, state string)
 	globalExitHook  func(ctx context.Context, state string)
+	initialState    *State
+	logger          Logger
+	logLevel        LogLevel
+	middleware      []Middleware
 }
 
 func NewFSM(name string) *FSM {
@@ -87,18 +92,82 @@ func (f *FSM) AddTransitionOn(from, to string, condition func(ctx context.Contex
 		log.Fatalf("\t[fsm] state not defined %s", to)
 		return nil
 	}
+	if f.getState(from).final {
+		log.Fatalf("\t[fsm] can not add transition from final state %s", from)
+		return nil
+	}
 	if !f.hasTransition(from, to) {
 		fromState := f.getState(from)
 		toState := f.getState(to)
 		f.transitions = append(f.transitions, NewTransition(fromState, toState, condition))
 	} else {
-		log.Printf("\t[fsm] Skipped add transition due to transition exists. from %s to %s\n",
-			from, to)
+		f.log(LogLevelWarn, "skipped add transition due to transition exists", "from", from, "to", to)
 	}
 
 	return f
 }
 
+// SetInitial declares the state an entity starts in, see GetInitialState.
+func (f *FSM) SetInitial(state string) *FSM {
+	s := f.getState(state)
+	if s == nil {
+		log.Fatalf("\t[fsm] state not defined %s", state)
+		return nil
+	}
+	f.initialState = s
+	return f
+}
+
+// AddFinalStates declares states that complete the fsm, they can not have outgoing transitions.
+func (f *FSM) AddFinalStates(state ...string) *FSM {
+	for _, name := range state {
+		s := f.getState(name)
+		if s == nil {
+			log.Fatalf("\t[fsm] state not defined %s", name)
+			return nil
+		}
+		if len(f.getAvailableTransitions(name)) > 0 {
+			log.Fatalf("\t[fsm] final state %s can not have outgoing transitions", name)
+			return nil
+		}
+		s.final = true
+	}
+	return f
+}
+
+// AddTransitionAction attaches behaviour to the transition from -> to rather than to a state,
+// e.g. refund on paid -> cancelled only. Actions run after the exit hooks of from,
+// an action error skips the enter hooks of to and is returned by Transit.
+func (f *FSM) AddTransitionAction(from, to string, action func(ctx context.Context, from, to string) error) *FSM {
+	t := f.getTransition(from, to)
+	if t == nil {
+		log.Fatalf("\t[fsm] transition not defined from %s to %s", from, to)
+		return nil
+	}
+	t.Actions = append(t.Actions, action)
+	return f
+}
+
+func (f *FSM) SetStateMeta(state string, meta StateMeta) *FSM {
+	s := f.getState(state)
+	if s == nil {
+		log.Fatalf("\t[fsm] state not defined %s", state)
+		return nil
+	}
+	s.Meta = meta
+	return f
+}
+
+func (f *FSM) SetTransitionMeta(from, to string, meta TransitionMeta) *FSM {
+	t := f.getTransition(from, to)
+	if t == nil {
+		log.Fatalf("\t[fsm] transition not defined from %s to %s", from, to)
+		return nil
+	}
+	t.Meta = meta
+	return f
+}
+
 /***** transit fsm  *****/
 
 // transit from current state to the given state
@@ -106,47 +175,89 @@ func (f *FSM) Transit(ctx context.Context, from, to string) error {
 	availableTransitions := f.getAvailableTransitions(from)
 	for _, transition := range availableTransitions {
 		if transition.To.Name == to {
-			log.Printf("\t[fsm] transit status to %s\n", to)
-			return f.doTransit(ctx, transition)
+			start := time.Now()
+			reached := false
+			err := f.chain(func(ctx context.Context, transition *Transition) error {
+				reached = true
+				return f.doTransit(ctx, transition)
+			})(ctx, transition)
+			if err != nil {
+				if !reached {
+					f.log(LogLevelWarn, "transit rejected by middleware", transitionArgs(transition, "error", err)...)
+				}
+				return err
+			}
+			f.log(LogLevelInfo, "transited", transitionArgs(transition, "duration", time.Since(start))...)
+			return nil
 		}
 	}
 	err := errors.New(fmt.Sprintf("\t[fsm] transition from %s to %s not found",
 		from, to))
-	log.Println(err.Error())
+	f.log(LogLevelWarn, "transit rejected", "from", from, "to", to, "error", err)
 	return err
 }
 
 // check condition and set state
 func (f *FSM) doTransit(ctx context.Context, transition *Transition) error {
-	log.Printf("\t[fsm] start condition check for transit(%s)\n", transition.Key)
 	if transition.Condition != nil {
+		f.log(LogLevelDebug, "start condition check", transitionArgs(transition)...)
 		if flag, err := transition.Condition(ctx, transition.From.Name); err != nil {
-			log.Printf("\t[fsm] transit(%s) condition check err %s\n", transition.Key, err)
+			f.log(LogLevelWarn, "transit rejected", transitionArgs(transition, "error", err)...)
 			return err
 		} else if flag == false {
 			err = errors.New(fmt.Sprintf("[fsm] transit(%s) condition not met", transition.Key))
-			log.Printf("\t[fsm] transit(%s) condition check err %s\n", transition.Key, err)
+			f.log(LogLevelWarn, "transit rejected", transitionArgs(transition, "error", err)...)
 			return err
 		}
 	} else {
-		log.Printf("\t[fsm] skipped condition check for transit(%s) due to condition is nil\n", transition.Key)
+		f.log(LogLevelDebug, "skipped condition check due to condition is nil", transitionArgs(transition)...)
 	}
 
-	f.setState(ctx, transition.To)
+	f.executeExitHooks(ctx, transition.From)
+	f.executeGlobalExitHook(ctx, transition.From)
+	if err := f.executeActions(ctx, transition); err != nil {
+		return err
+	}
+	f.setState(ctx, transition.From.Name, transition.To)
 	return nil
 }
 
-// setState will execute state hooks and global hooks
-func (f *FSM) setState(ctx context.Context, state *State) {
+// setState will execute enter hooks and the global enter hook of state,
+// exit hooks of the state left run before the actions, see doTransit
+func (f *FSM) setState(ctx context.Context, from string, state *State) {
 	f.executeGlobalEnterHook(ctx, state)
-	f.executeEnterHook(ctx, state)
-
-	f.executeExitHook(ctx, state)
-	f.executeGlobalExitHook(ctx, state)
+	f.executeEnterHooks(ctx, from, state)
 }
 
 /***** retrieve fsm  *****/
 
+func (f *FSM) Name() string {
+	return f.name
+}
+
+// States returns all states in the order they are added.
+func (f *FSM) States() []*State {
+	return append([]*State(nil), f.states...)
+}
+
+// Transitions returns all transitions in the order they are added.
+func (f *FSM) Transitions() []*Transition {
+	return append([]*Transition(nil), f.transitions...)
+}
+
+func (f *FSM) GetInitialState() string {
+	if f.initialState == nil {
+		return ""
+	}
+	return f.initialState.Name
+}
+
+// IsFinal reports whether state is a final state.
+func (f *FSM) IsFinal(state string) bool {
+	s := f.getState(state)
+	return s != nil && s.final
+}
+
 func (f *FSM) GetAvailableStateNames(from string) []string {
 	states := f.getAvailableStates(from)
 	names := make([]string, 0)
@@ -1,19 +1,94 @@
 package singletonfsm
 
-import (
-	"context"
-	"fmt"
-)
+import "context"
 
-func (f *FSM) AddStateEnterHook(state string, hook func(ctx context.Context, state string)) *FSM {
+// Hook is a state hook, lower Priority runs first, equal priorities run in the order added.
+// A named hook can be removed, FromStates limits an enter hook to transitions from those states.
+type Hook struct {
+	Name       string
+	Priority   int
+	FromStates []string
+	Func       func(ctx context.Context, state string)
+}
+
+type HookOption func(h *Hook)
+
+func NewHook(hook func(ctx context.Context, state string), opts ...HookOption) *Hook {
+	h := &Hook{Func: hook}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// WithHookName names a hook so it can be removed with RemoveStateEnterHook or RemoveStateExitHook.
+func WithHookName(name string) HookOption {
+	return func(h *Hook) {
+		h.Name = name
+	}
+}
+
+// WithPriority orders hooks of a state, default is 0 and lower runs first.
+func WithPriority(priority int) HookOption {
+	return func(h *Hook) {
+		h.Priority = priority
+	}
+}
+
+// FromStates runs an enter hook only when the state is entered from one of states, exit hooks ignore it.
+func FromStates(states ...string) HookOption {
+	return func(h *Hook) {
+		h.FromStates = states
+	}
+}
+
+// runsFrom reports whether the hook applies to a transition from the given state.
+func (h *Hook) runsFrom(from string) bool {
+	if len(h.FromStates) == 0 {
+		return true
+	}
+	for _, s := range h.FromStates {
+		if s == from {
+			return true
+		}
+	}
+	return false
+}
+
+// AddStateEnterHook and AddStateExitHook add a hook to the hooks of state,
+// they skip undefined states with a warning
+func (f *FSM) AddStateEnterHook(state string, hook func(ctx context.Context, state string), opts ...HookOption) *FSM {
 	s := f.getState(state)
-	s.SetEnterHook(hook)
+	if s == nil {
+		f.log(LogLevelWarn, "skipped add enter hook due to state not defined", "state", state)
+		return f
+	}
+	s.AddEnterHook(NewHook(hook, opts...))
 	return f
 }
 
-func (f *FSM) AddStateExitHook(state string, hook func(ctx context.Context, state string)) *FSM {
+func (f *FSM) AddStateExitHook(state string, hook func(ctx context.Context, state string), opts ...HookOption) *FSM {
 	s := f.getState(state)
-	s.SetExitHook(hook)
+	if s == nil {
+		f.log(LogLevelWarn, "skipped add exit hook due to state not defined", "state", state)
+		return f
+	}
+	s.AddExitHook(NewHook(hook, opts...))
+	return f
+}
+
+// RemoveStateEnterHook and RemoveStateExitHook remove hooks added with WithHookName.
+func (f *FSM) RemoveStateEnterHook(state, name string) *FSM {
+	if s := f.getState(state); s != nil {
+		s.RemoveEnterHook(name)
+	}
+	return f
+}
+
+func (f *FSM) RemoveStateExitHook(state, name string) *FSM {
+	if s := f.getState(state); s != nil {
+		s.RemoveExitHook(name)
+	}
 	return f
 }
 
@@ -31,7 +106,7 @@ func (f *FSM) AddGlobalExitHook(hook func(ctx context.Context, state string)) *F
 
 func (f *FSM) executeHook(ctx context.Context, state *State, hook func(ctx context.Context, state string)) {
 	if hook != nil {
-		fmt.Printf("\t[fsm] start execute hook for state %s\n", state.Name)
+		f.log(LogLevelDebug, "start execute hook", "state", state.Name)
 		hook(ctx, state.Name)
 	}
 }
@@ -46,10 +121,29 @@ func (f *FSM) executeGlobalExitHook(ctx context.Context, state *State) {
 	f.executeHook(ctx, state, f.globalExitHook)
 }
 
-func (f *FSM) executeEnterHook(ctx context.Context, state *State) {
-	f.executeHook(ctx, state, state.enterHook)
+// executeEnterHooks runs enter hooks of state for a transition from the given state
+func (f *FSM) executeEnterHooks(ctx context.Context, from string, state *State) {
+	for _, h := range state.enterHooks {
+		if h.runsFrom(from) {
+			f.executeHook(ctx, state, h.Func)
+		}
+	}
+}
+
+func (f *FSM) executeExitHooks(ctx context.Context, state *State) {
+	for _, h := range state.exitHooks {
+		f.executeHook(ctx, state, h.Func)
+	}
 }
 
-func (f *FSM) executeExitHook(ctx context.Context, state *State) {
-	f.executeHook(ctx, state, state.exitHook)
+// executeActions runs actions of transition, it stops at the first error.
+func (f *FSM) executeActions(ctx context.Context, transition *Transition) error {
+	for _, action := range transition.Actions {
+		f.log(LogLevelDebug, "start execute action", transitionArgs(transition)...)
+		if err := action(ctx, transition.From.Name, transition.To.Name); err != nil {
+			f.log(LogLevelWarn, "transit rejected", transitionArgs(transition, "error", err)...)
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,60 @@
+package singletonfsm_test
+
+import (
+	"context"
+	"github.com/FingerLiu/go-fsm/singletonfsm"
+	"reflect"
+	"testing"
+)
+
+func TestHookOrder(t *testing.T) {
+	var calls []string
+	record := func(name string) func(ctx context.Context, state string) {
+		return func(ctx context.Context, state string) {
+			calls = append(calls, name+" "+state)
+		}
+	}
+	f := order().
+		AddStateExitHook("created", record("exit")).
+		AddStateEnterHook("paid", record("audit"), singletonfsm.WithPriority(100)).
+		AddStateEnterHook("paid", record("enter")).
+		AddStateEnterHook("paid", record("refund"), singletonfsm.WithHookName("refund")).
+		RemoveStateEnterHook("paid", "refund").
+		AddGlobalExitHook(record("global exit")).
+		AddGlobalEnterHook(record("global enter")).
+		AddTransitionAction("created", "paid", func(ctx context.Context, from, to string) error {
+			calls = append(calls, "action "+from+">"+to)
+			return nil
+		})
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"exit created",
+		"global exit created",
+		"action created>paid",
+		"global enter paid",
+		"enter paid",
+		"audit paid",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+}
+
+func TestHookOnUndefinedState(t *testing.T) {
+	logger := &memoryLogger{}
+	f := order().SetLogger(logger).
+		AddStateEnterHook("missing", func(ctx context.Context, state string) {}).
+		AddStateExitHook("missing", func(ctx context.Context, state string) {})
+	want := []string{
+		"WARN skipped add enter hook due to state not defined machine=order state=missing",
+		"WARN skipped add exit hook due to state not defined machine=order state=missing",
+	}
+	if !reflect.DeepEqual(logger.records, want) {
+		t.Errorf("records %q\nwant %q", logger.records, want)
+	}
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,89 @@
+package singletonfsm_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/FingerLiu/go-fsm/singletonfsm"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// memoryLogger keeps records as "LEVEL msg key=value ...".
+type memoryLogger struct {
+	records []string
+}
+
+func (l *memoryLogger) record(level, msg string, args []interface{}) {
+	s := level + " " + msg
+	for i := 0; i+1 < len(args); i += 2 {
+		s += fmt.Sprintf(" %v=%v", args[i], args[i+1])
+	}
+	l.records = append(l.records, s)
+}
+
+func (l *memoryLogger) Debug(msg string, args ...interface{}) { l.record("DEBUG", msg, args) }
+func (l *memoryLogger) Info(msg string, args ...interface{})  { l.record("INFO", msg, args) }
+func (l *memoryLogger) Warn(msg string, args ...interface{})  { l.record("WARN", msg, args) }
+func (l *memoryLogger) Error(msg string, args ...interface{}) { l.record("ERROR", msg, args) }
+
+func order() *singletonfsm.FSM {
+	return singletonfsm.NewFSM("order").AddStates("created", "paid").
+		AddTransition("created", "paid").
+		SetTransitionMeta("created", "paid", singletonfsm.TransitionMeta{Label: "pay"})
+}
+
+func TestLoggerSilentByDefault(t *testing.T) {
+	var buf bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&buf)
+	f := order()
+	f.Transit(context.Background(), "paid", "created")
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("nil logger writes %q", buf.String())
+	}
+}
+
+func TestLoggerFields(t *testing.T) {
+	logger := &memoryLogger{}
+	f := order().SetLogger(logger)
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"DEBUG skipped condition check due to condition is nil machine=order from=created to=paid event=pay",
+		"INFO transited machine=order from=created to=paid event=pay duration=",
+	}
+	if len(logger.records) != 2 || logger.records[0] != want[0] || !strings.HasPrefix(logger.records[1], want[1]) {
+		t.Errorf("records %q\nwant %q", logger.records, want)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	logger := &memoryLogger{}
+	f := order().SetLogger(logger).SetLogLevel(singletonfsm.LogLevelWarn)
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	f.Transit(context.Background(), "paid", "created")
+	want := []string{"WARN transit rejected machine=order from=paid to=created error=\t[fsm] transition from paid to created not found"}
+	if !reflect.DeepEqual(logger.records, want) {
+		t.Errorf("records %q\nwant %q", logger.records, want)
+	}
+}
+
+func TestStdLogger(t *testing.T) {
+	var buf bytes.Buffer
+	f := order().SetLogger(singletonfsm.StdLogger(log.New(&buf, "", 0))).SetLogLevel(singletonfsm.LogLevelInfo)
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[fsm] INFO transited machine=order from=created to=paid event=pay duration="; !strings.HasPrefix(buf.String(), want) {
+		t.Errorf("std logger writes %q, want %q...", buf.String(), want)
+	}
+}
@@ -0,0 +1,56 @@
+package singletonfsm_test
+
+import (
+	"context"
+	"errors"
+	"github.com/FingerLiu/go-fsm/singletonfsm"
+	"reflect"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	var calls []string
+	trace := func(name string) singletonfsm.Middleware {
+		return func(next singletonfsm.TransitFunc) singletonfsm.TransitFunc {
+			return func(ctx context.Context, transition *singletonfsm.Transition) error {
+				calls = append(calls, name+" "+transition.Key)
+				return next(ctx, transition)
+			}
+		}
+	}
+	f := order().
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {
+			calls = append(calls, "enter "+state)
+		}).
+		Use(trace("outer"), trace("inner"))
+	if err := f.Transit(context.Background(), "created", "paid"); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"outer created->paid", "inner created->paid", "enter paid"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("calls %v\nwant %v", calls, want)
+	}
+
+	// transitions that do not exist skip the middleware
+	calls = nil
+	if err := f.Transit(context.Background(), "paid", "created"); err == nil || len(calls) != 0 {
+		t.Errorf("undefined transition returns %v, calls %v", err, calls)
+	}
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	forbidden := errors.New("forbidden")
+	entered := false
+	f := order().
+		AddStateEnterHook("paid", func(ctx context.Context, state string) {
+			entered = true
+		}).
+		Use(func(next singletonfsm.TransitFunc) singletonfsm.TransitFunc {
+			return func(ctx context.Context, transition *singletonfsm.Transition) error {
+				return forbidden
+			}
+		})
+	if err := f.Transit(context.Background(), "created", "paid"); err != forbidden || entered {
+		t.Errorf("Transit returns %v, hook ran %v", err, entered)
+	}
+}
@@ -10,6 +10,18 @@ type Transition struct {
 	To        *State
 	Key       string
 	Condition func(ctx context.Context, currentState string) (bool, error)
+	// Actions run in order once the condition passed, before hooks of the target state
+	Actions []func(ctx context.Context, from, to string) error
+	Meta    TransitionMeta
+}
+
+// TransitionMeta documents a transition and controls how it is drawn.
+// Empty fields fall back to defaults.
+type TransitionMeta struct {
+	Label       string
+	Description string
+	Color       string
+	Tags        []string
 }
 
 func NewTransition(from, to *State, condition func(ctx context.Context, currentState string) (bool, error)) *Transition {